@@ -1,146 +1,170 @@
 package api
 
 import (
-	"bufio"
 	"bytes"
 	"encoding/json"
-	"fmt"
-	"log"
-	"os"
-	"os/user"
-	"path/filepath"
 	"reflect"
+	"sort"
 	"strings"
+	"sync"
 
 	vault "github.com/hashicorp/vault/api"
-	"github.com/jroimartin/gocui"
 )
 
 var cl *vault.Client
+var clmu sync.RWMutex
 
-func init() {
+// Connect creates the client from the environment the same way the vault
+// CLI does. A missing token is not an error, the UI asks the user to log
+// in.
+func Connect() error {
 	c := vault.DefaultConfig()
-	cl, _ = vault.NewClient(c)
-	token := vaultToken()
-	cl.SetToken(token)
+	if c.Error != nil {
+		return c.Error
+	}
+	newcl, err := vault.NewClient(c)
+	if err != nil {
+		return err
+	}
+	if token, err := vaultToken(); err == nil {
+		newcl.SetToken(token)
+	}
+	setClient(newcl)
+	return nil
 }
 
-func ListMounts() []string {
-	mounts, _ := cl.Sys().ListMounts()
-	var mountsWithKeys []string
-	for k, l := range mounts {
-		if mountCheck(k, l) {
-			mountsWithKeys = append(mountsWithKeys, k)
-		}
-	}
-	return mountsWithKeys
+// client returns the client requests are made with. It is replaced when
+// switching profiles, so it is looked up for every request.
+func client() *vault.Client {
+	clmu.RLock()
+	defer clmu.RUnlock()
+	return cl
 }
 
-func ListAllKeys(keys []string, path string, parent string, v *gocui.View) {
-	parent = fmt.Sprintf("%s%s", parent, path)
-	vaultListing := listKeys(parent)
-	for _, key := range vaultListing {
-		if strings.HasSuffix(key.(string), "/") {
-			ListAllKeys(keys, key.(string), parent, v)
-		} else {
-			fmt.Fprintln(v, parent+key.(string))
-			keys = append(keys, parent+key.(string))
-		}
-	}
+func setClient(c *vault.Client) {
+	clmu.Lock()
+	cl = c
+	clmu.Unlock()
+	setMounts(map[string]mountInfo{})
+	clearCache()
 }
 
-func listKeys(path string) []interface{} {
-	resp, err := cl.Logical().List(path)
-	if err != nil {
-		fmt.Println(err)
-	}
+// Address returns the address of the Vault server in use.
+func Address() string {
+	return client().Address()
+}
 
-	if resp == nil {
-		return nil
-	}
+// Namespace returns the Vault Enterprise namespace requests are made in.
+func Namespace() string {
+	return client().Namespace()
+}
 
-	if slice, ok := resp.Data["keys"]; ok {
-		return slice.([]interface{})
+// SetNamespace switches all following requests to namespace ns, "" being
+// the root namespace.
+func SetNamespace(ns string) {
+	ns = strings.Trim(ns, "/")
+	if ns == "" {
+		client().ClearNamespace()
 	} else {
-		return nil
+		client().SetNamespace(ns + "/")
 	}
-	return nil
+	setMounts(map[string]mountInfo{})
+	clearCache()
 }
 
-func mountCheck(path string, t *vault.MountOutput) bool {
-	_, err := cl.Logical().List(path)
-	if t.Type != "generic" && t.Type != "cubbyhole" {
-		return false
+// ListNamespaces returns the child namespaces of the current namespace.
+func ListNamespaces() ([]string, error) {
+	resp, err := client().Logical().List("sys/namespaces")
+	if err != nil {
+		return nil, err
+	}
+	if resp == nil {
+		return nil, nil
 	}
 
-	if err != nil {
-		return false
+	keys, _ := resp.Data["keys"].([]interface{})
+	namespaces := make([]string, 0, len(keys))
+	for _, k := range keys {
+		if ns, ok := k.(string); ok {
+			namespaces = append(namespaces, ns)
+		}
 	}
-	return true
+	sort.Strings(namespaces)
+	return namespaces, nil
 }
 
-func vaultToken() string {
-	usr, err := user.Current()
-	if err != nil {
-		log.Fatal(err)
+func ListMounts() []string {
+	mountlist, _ := client().Sys().ListMounts()
+	var mountsWithKeys []string
+	seen := map[string]mountInfo{}
+	for k, l := range mountlist {
+		if mountCheck(k, l) {
+			mountsWithKeys = append(mountsWithKeys, k)
+			seen[k] = mountInfo{path: k, version: kvVersion(l)}
+		}
 	}
+	setMounts(seen)
+	return mountsWithKeys
+}
 
-	path := filepath.Join(usr.HomeDir, ".vault-token")
-
-	file, err := os.Open(path)
-	if err != nil {
-		log.Fatal(err)
+func mountCheck(path string, t *vault.MountOutput) bool {
+	if t.Type != "generic" && t.Type != "cubbyhole" && t.Type != "kv" {
+		return false
 	}
-	defer file.Close()
 
-	scanner := bufio.NewScanner(file)
-
-	var vault_token string
-
-	// vault_token file is only one line
-	for scanner.Scan() {
-		vault_token = scanner.Text()
+	listpath := path
+	if kvVersion(t) == 2 {
+		listpath = path + "metadata/"
 	}
+	_, err := client().Logical().List(listpath)
 
-	if err := scanner.Err(); err != nil {
-		log.Fatal(err)
+	if err != nil {
+		return false
 	}
-
-	return vault_token
+	return true
 }
 
-func ReadValue(path string, v *gocui.View) string {
-	resp, err := cl.Logical().Read(path)
+func encodeData(data map[string]interface{}) string {
 	buf := new(bytes.Buffer)
 	enc := json.NewEncoder(buf)
 	enc.SetEscapeHTML(false)
 	enc.SetIndent("", "\t")
 
-	if err != nil {
-		fmt.Println(err)
-	}
-
-	enc.Encode(&resp.Data)
+	enc.Encode(&data)
 	return string(buf.String())
 }
 
-func ComparePathToValue(path string, contents map[string]interface{}) bool {
-	resp, err := cl.Logical().Read(path)
+func ComparePathToValue(path string, contents map[string]interface{}) (bool, error) {
+	resp, err := client().Logical().Read(dataPath(path))
 	if err != nil {
-		log.Panicln(err)
+		return false, err
 	}
-	if reflect.DeepEqual(contents, resp.Data) {
-		return true
+	if reflect.DeepEqual(contents, secretData(path, resp)) {
+		return true, nil
 	}
-	return false
+	return false, nil
 }
 
 func Write(secretpath string, mdata map[string]interface{}) error {
-	_, err := cl.Logical().Write(secretpath, mdata)
+	if IsKVv2(secretpath) {
+		mdata = map[string]interface{}{"data": mdata}
+	}
+	_, err := client().Logical().Write(dataPath(secretpath), mdata)
+	invalidate(secretpath)
 	return err
 }
 
 func Delete(secretpath string) error {
-	_, err := cl.Logical().Delete(secretpath)
+	_, err := client().Logical().Delete(dataPath(secretpath))
+	invalidate(secretpath)
+	return err
+}
+
+// DeleteMetadata deletes the secret at secretpath with every version and
+// its metadata, so it is no longer listed. On KV v1 it is the same as
+// Delete.
+func DeleteMetadata(secretpath string) error {
+	_, err := client().Logical().Delete(metadataPath(secretpath))
+	invalidate(secretpath)
 	return err
 }
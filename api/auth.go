@@ -0,0 +1,165 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	vault "github.com/hashicorp/vault/api"
+)
+
+// AuthMethods are the login methods supported by Login.
+var AuthMethods = []string{"token", "userpass", "ldap", "approle"}
+
+// DefaultAuthMethod returns the auth method of the profile in use, or ""
+// if it doesn't set one.
+func DefaultAuthMethod() string {
+	if profile == nil {
+		return ""
+	}
+	return profile.AuthMethod
+}
+
+// ErrNoToken is returned by CheckToken when no token was found.
+var ErrNoToken = errors.New("no Vault token found")
+
+// LoginField is one value the user has to enter to log in.
+type LoginField struct {
+	Name    string
+	Secret  bool
+	Default string
+}
+
+// LoginFields returns the fields Login expects for an auth method.
+func LoginFields(method string) []LoginField {
+	switch method {
+	case "token":
+		return []LoginField{{Name: "token", Secret: true}}
+	case "userpass", "ldap":
+		return []LoginField{
+			{Name: "mount", Default: method},
+			{Name: "username"},
+			{Name: "password", Secret: true},
+		}
+	case "approle":
+		return []LoginField{
+			{Name: "mount", Default: method},
+			{Name: "role_id"},
+			{Name: "secret_id", Secret: true},
+		}
+	}
+	return nil
+}
+
+// Login authenticates with the given method and switches the client to the
+// resulting token, which is returned so it can be saved.
+func Login(method string, params map[string]string) (string, error) {
+	mount := params["mount"]
+	if mount == "" {
+		mount = method
+	}
+
+	var token string
+	switch method {
+	case "token":
+		token = params["token"]
+	case "userpass", "ldap":
+		auth, err := loginRequest(fmt.Sprintf("auth/%s/login/%s", mount, params["username"]), map[string]interface{}{
+			"password": params["password"],
+		})
+		if err != nil {
+			return "", err
+		}
+		token = auth.ClientToken
+	case "approle":
+		auth, err := loginRequest(fmt.Sprintf("auth/%s/login", mount), map[string]interface{}{
+			"role_id":   params["role_id"],
+			"secret_id": params["secret_id"],
+		})
+		if err != nil {
+			return "", err
+		}
+		token = auth.ClientToken
+	default:
+		return "", fmt.Errorf("unsupported auth method %q", method)
+	}
+
+	// Check the new token on a copy of the client so requests running in
+	// the background keep using the old one until it is known to work.
+	c, err := client().Clone()
+	if err != nil {
+		return "", err
+	}
+	c.SetToken(token)
+	if _, err := c.Auth().Token().LookupSelf(); err != nil {
+		return "", err
+	}
+
+	client().SetToken(token)
+	clearCache()
+	return token, nil
+}
+
+func loginRequest(path string, data map[string]interface{}) (*vault.SecretAuth, error) {
+	c, err := client().Clone()
+	if err != nil {
+		return nil, err
+	}
+	c.ClearToken()
+	resp, err := c.Logical().Write(path, data)
+	if err != nil {
+		return nil, err
+	}
+	if resp == nil || resp.Auth == nil {
+		return nil, fmt.Errorf("no token returned by %s", path)
+	}
+	return resp.Auth, nil
+}
+
+// CheckToken verifies that the client has a token Vault accepts.
+func CheckToken() error {
+	if client().Token() == "" {
+		return ErrNoToken
+	}
+	_, err := client().Auth().Token().LookupSelf()
+	return err
+}
+
+// IsForbidden reports whether err is a 403 response from Vault.
+func IsForbidden(err error) bool {
+	re, ok := err.(*vault.ResponseError)
+	return ok && re.StatusCode == 403
+}
+
+// TokenInfo is what the UI shows and acts on about the current token.
+type TokenInfo struct {
+	DisplayName string
+	Policies    []string
+	TTL         time.Duration
+	Renewable   bool
+}
+
+// LookupToken looks up the current token. A TTL of 0 means the token
+// does not expire.
+func LookupToken() (*TokenInfo, error) {
+	resp, err := client().Auth().Token().LookupSelf()
+	if err != nil {
+		return nil, err
+	}
+
+	info := &TokenInfo{}
+	info.DisplayName, _ = resp.Data["display_name"].(string)
+	info.Policies, _ = resp.TokenPolicies()
+	info.TTL, _ = resp.TokenTTL()
+	info.Renewable, _ = resp.TokenIsRenewable()
+	return info, nil
+}
+
+// RenewToken renews the current token and returns its new TTL.
+func RenewToken() (time.Duration, error) {
+	resp, err := client().Auth().Token().RenewSelf(0)
+	if err != nil {
+		return 0, err
+	}
+	return resp.TokenTTL()
+}
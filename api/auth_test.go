@@ -0,0 +1,119 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	vault "github.com/hashicorp/vault/api"
+)
+
+// fakeVault points the package client at a local HTTP server for the
+// duration of a test.
+func fakeVault(t *testing.T, handler http.Handler) {
+	srv := httptest.NewServer(handler)
+	c := vault.DefaultConfig()
+	c.Address = srv.URL
+	client, err := vault.NewClient(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.ClearToken()
+
+	old := cl
+	cl = client
+	t.Cleanup(func() {
+		cl = old
+		srv.Close()
+	})
+}
+
+func reply(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func authServer(valid map[string]bool) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/auth/token/lookup-self", func(w http.ResponseWriter, r *http.Request) {
+		token := r.Header.Get("X-Vault-Token")
+		if !valid[token] {
+			reply(w, 403, map[string]interface{}{"errors": []string{"permission denied"}})
+			return
+		}
+		reply(w, 200, map[string]interface{}{"data": map[string]interface{}{"id": token}})
+	})
+	mux.HandleFunc("/v1/auth/userpass/login/alice", func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		if body["password"] != "hunter2" {
+			reply(w, 400, map[string]interface{}{"errors": []string{"invalid username or password"}})
+			return
+		}
+		reply(w, 200, map[string]interface{}{"auth": map[string]interface{}{"client_token": "s.userpass"}})
+	})
+	mux.HandleFunc("/v1/auth/approle/login", func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		if body["role_id"] != "role" || body["secret_id"] != "secret" {
+			reply(w, 400, map[string]interface{}{"errors": []string{"invalid role or secret ID"}})
+			return
+		}
+		reply(w, 200, map[string]interface{}{"auth": map[string]interface{}{"client_token": "s.approle"}})
+	})
+	return mux
+}
+
+func TestLoginUserpass(t *testing.T) {
+	fakeVault(t, authServer(map[string]bool{"s.userpass": true}))
+
+	token, err := Login("userpass", map[string]string{"username": "alice", "password": "hunter2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "s.userpass" || cl.Token() != "s.userpass" {
+		t.Errorf("Test failed, expected: 's.userpass', got:  '%s' '%s'", token, cl.Token())
+	}
+
+	if _, err := Login("userpass", map[string]string{"username": "alice", "password": "wrong"}); err == nil {
+		t.Error("Test failed, expected an error for a wrong password")
+	}
+	if cl.Token() != "s.userpass" {
+		t.Errorf("Test failed, expected the previous token to be kept, got:  '%s'", cl.Token())
+	}
+}
+
+func TestLoginAppRole(t *testing.T) {
+	fakeVault(t, authServer(map[string]bool{"s.approle": true}))
+
+	token, err := Login("approle", map[string]string{"role_id": "role", "secret_id": "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "s.approle" {
+		t.Errorf("Test failed, expected: 's.approle', got:  '%s'", token)
+	}
+}
+
+func TestLoginToken(t *testing.T) {
+	fakeVault(t, authServer(map[string]bool{"s.good": true}))
+
+	if _, err := Login("token", map[string]string{"token": "s.good"}); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := Login("token", map[string]string{"token": "s.bad"})
+	if !IsForbidden(err) {
+		t.Errorf("Test failed, expected a 403, got:  '%v'", err)
+	}
+}
+
+func TestCheckTokenMissing(t *testing.T) {
+	fakeVault(t, authServer(nil))
+
+	if err := CheckToken(); err != ErrNoToken {
+		t.Errorf("Test failed, expected: '%v', got:  '%v'", ErrNoToken, err)
+	}
+}
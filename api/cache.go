@@ -0,0 +1,123 @@
+package api
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	vault "github.com/hashicorp/vault/api"
+)
+
+// CacheTTL is how long listings and secrets are served from the cache
+// before they are fetched from Vault again.
+var CacheTTL = time.Minute
+
+type listEntry struct {
+	keys    []string
+	fetched time.Time
+}
+
+type readEntry struct {
+	resp    *vault.Secret
+	fetched time.Time
+}
+
+// listcache and readcache hold folder listings and read responses by
+// logical path. Writes and deletes drop the entries they make stale.
+var cachemu sync.Mutex
+var listcache = map[string]listEntry{}
+var readcache = map[string]readEntry{}
+
+func cachedList(path string) ([]string, bool) {
+	cachemu.Lock()
+	defer cachemu.Unlock()
+	e, ok := listcache[path]
+	if !ok || time.Since(e.fetched) > CacheTTL {
+		return nil, false
+	}
+	return append([]string(nil), e.keys...), true
+}
+
+func cacheList(path string, keys []string) {
+	cachemu.Lock()
+	listcache[path] = listEntry{keys: append([]string(nil), keys...), fetched: time.Now()}
+	cachemu.Unlock()
+}
+
+type nocacheKey struct{}
+
+// NoCache returns a context for reads that must come from Vault rather
+// than the cache, such as the current value of a secret in a conflict.
+func NoCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, nocacheKey{}, true)
+}
+
+// readSecret reads the secret at path, from the cache when it was read
+// recently unless ctx comes from NoCache.
+func readSecret(ctx context.Context, path string) (*vault.Secret, error) {
+	if ctx.Value(nocacheKey{}) == nil {
+		cachemu.Lock()
+		e, ok := readcache[path]
+		cachemu.Unlock()
+		if ok && time.Since(e.fetched) <= CacheTTL {
+			return e.resp, nil
+		}
+	}
+
+	resp, err := client().Logical().ReadWithContext(ctx, dataPath(path))
+	if err != nil {
+		return nil, err
+	}
+	cachemu.Lock()
+	readcache[path] = readEntry{resp: resp, fetched: time.Now()}
+	cachemu.Unlock()
+	return resp, nil
+}
+
+// invalidate drops the cached read of path and the listings of the folders
+// above it, whose entries change when a secret is created or removed.
+func invalidate(path string) {
+	cachemu.Lock()
+	defer cachemu.Unlock()
+	delete(readcache, path)
+	delete(listcache, path)
+	for i := 0; i < len(path); i++ {
+		if path[i] == '/' {
+			delete(listcache, path[:i+1])
+		}
+	}
+}
+
+func clearCache() {
+	cachemu.Lock()
+	listcache = map[string]listEntry{}
+	readcache = map[string]readEntry{}
+	cachemu.Unlock()
+}
+
+// Refresh drops every cached listing and secret under prefix so they are
+// fetched from Vault again.
+func Refresh(prefix string) {
+	cachemu.Lock()
+	defer cachemu.Unlock()
+	for path := range listcache {
+		if strings.HasPrefix(path, prefix) {
+			delete(listcache, path)
+		}
+	}
+	for path := range readcache {
+		if strings.HasPrefix(path, prefix) {
+			delete(readcache, path)
+		}
+	}
+}
+
+// ListedAt returns when the listing of path was fetched from Vault, false
+// if it isn't cached.
+func ListedAt(path string) (time.Time, bool) {
+	cachemu.Lock()
+	defer cachemu.Unlock()
+	e, ok := listcache[path]
+	return e.fetched, ok
+}
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"testing"
+	"time"
+)
+
+func TestInvalidate(t *testing.T) {
+	clearCache()
+	defer clearCache()
+	for _, folder := range []string{"secret/", "secret/app/", "secret/app/db/", "secret/web/"} {
+		cacheList(folder, []string{"key"})
+	}
+
+	invalidate("secret/app/db/password")
+
+	for folder, expected := range map[string]bool{
+		"secret/":        false,
+		"secret/app/":    false,
+		"secret/app/db/": false,
+		"secret/web/":    true,
+	} {
+		if _, ok := cachedList(folder); ok != expected {
+			t.Errorf("Test failed, expected %s cached: '%v', got:  '%v'", folder, expected, ok)
+		}
+	}
+}
+
+func TestCacheExpires(t *testing.T) {
+	clearCache()
+	defer clearCache()
+	ttl := CacheTTL
+	defer func() { CacheTTL = ttl }()
+
+	cacheList("secret/", []string{"a", "b"})
+	keys, ok := cachedList("secret/")
+	if !ok || len(keys) != 2 {
+		t.Fatalf("Test failed, expected: '[a b]', got:  '%v'", keys)
+	}
+
+	CacheTTL = time.Nanosecond
+	time.Sleep(time.Millisecond)
+	if _, ok := cachedList("secret/"); ok {
+		t.Error("Test failed, expected the listing to have expired")
+	}
+	if _, ok := ListedAt("secret/"); !ok {
+		t.Error("Test failed, expected the listing time to be kept")
+	}
+
+	Refresh("secret/")
+	if _, ok := ListedAt("secret/"); ok {
+		t.Error("Test failed, expected the listing to be dropped")
+	}
+}
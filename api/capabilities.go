@@ -0,0 +1,41 @@
+package api
+
+// Capabilities is what the current token may do on a path.
+type Capabilities struct {
+	Create bool
+	Read   bool
+	Update bool
+	Delete bool
+	List   bool
+}
+
+// PathCapabilities asks sys/capabilities-self what the token may do on a
+// logical secret path. Paths ending in "/" stand for new secrets created
+// under that folder.
+func PathCapabilities(path string) (Capabilities, error) {
+	caps, err := client().Sys().CapabilitiesSelf(dataPath(path))
+	if err != nil {
+		return Capabilities{}, err
+	}
+
+	var c Capabilities
+	for _, capability := range caps {
+		switch capability {
+		case "root":
+			return Capabilities{true, true, true, true, true}, nil
+		case "deny":
+			return Capabilities{}, nil
+		case "create":
+			c.Create = true
+		case "read":
+			c.Read = true
+		case "update":
+			c.Update = true
+		case "delete":
+			c.Delete = true
+		case "list":
+			c.List = true
+		}
+	}
+	return c, nil
+}
@@ -0,0 +1,173 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// CopyPair is a secret and the path it is copied to.
+type CopyPair struct {
+	From string
+	To   string
+}
+
+// CopyDestination returns where copying src to dst copies it: dst itself,
+// or when dst is a bare name without a folder, that name in the folder src
+// is in.
+func CopyDestination(src string, dst string) string {
+	if dst == "" || strings.Contains(strings.TrimSuffix(dst, "/"), "/") {
+		return dst
+	}
+	parent := path.Dir(strings.TrimSuffix(src, "/"))
+	if parent == "." {
+		return dst
+	}
+	return parent + "/" + dst
+}
+
+// copyworkers bounds how many secrets CopyPairs reads at once.
+var copyworkers = 16
+
+// CopyPairs returns the secrets of s that copying src to dst copies and
+// where to, with dst resolved by CopyDestination. A folder src is copied
+// with everything under it into the folder dst, leaving out the secrets
+// whose current version is deleted, which it returns as skipped. A secret
+// src is copied to dst, or into it when dst is a folder. It fails if dst
+// is not under a mount of s, if any of the destinations already holds a
+// secret, or if dst is inside src.
+func CopyPairs(ctx context.Context, s SecretStore, src string, dst string) (pairs []CopyPair, skipped []string, err error) {
+	dst = CopyDestination(src, dst)
+	if dst == "" || dst == src {
+		return nil, nil, fmt.Errorf("give a destination other than %s", src)
+	}
+	if !underMount(s, dst) {
+		return nil, nil, fmt.Errorf("%s is not under a mount", dst)
+	}
+
+	if strings.HasSuffix(src, "/") {
+		if !strings.HasSuffix(dst, "/") {
+			dst += "/"
+		}
+		if strings.HasPrefix(dst, src) {
+			return nil, nil, fmt.Errorf("unable to copy %s into itself", src)
+		}
+		keys, err := ListAllKeys(ctx, s, src, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		keys, skipped, err = liveKeys(ctx, s, keys)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, key := range keys {
+			pairs = append(pairs, CopyPair{From: key, To: dst + strings.TrimPrefix(key, src)})
+		}
+	} else {
+		if strings.HasSuffix(dst, "/") {
+			dst += path.Base(src)
+		}
+		pairs = append(pairs, CopyPair{From: src, To: dst})
+	}
+	if len(pairs) == 0 {
+		return nil, nil, fmt.Errorf("no secrets under %s", src)
+	}
+
+	existing := map[string]bool{}
+	listed := map[string]bool{}
+	for _, pair := range pairs {
+		folder := pair.To[:strings.LastIndex(pair.To, "/")+1]
+		if !listed[folder] {
+			listed[folder] = true
+			keys, err := s.List(ctx, folder)
+			if err != nil {
+				return nil, nil, err
+			}
+			for _, key := range keys {
+				existing[folder+key] = true
+			}
+		}
+		if existing[pair.To] {
+			return nil, nil, fmt.Errorf("%s already exists", pair.To)
+		}
+	}
+	return pairs, skipped, nil
+}
+
+// liveKeys reads the secrets at keys and splits them into those that hold
+// data and those whose current version is deleted, both sorted. KV v2
+// keeps listing a secret after its current version is deleted.
+func liveKeys(ctx context.Context, s SecretStore, keys []string) (live []string, deleted []string, err error) {
+	var mu sync.Mutex
+	_, err = ForEach(ctx, keys, copyworkers, func(ctx context.Context, key string) error {
+		secret, err := s.Read(ctx, key)
+		if err != nil {
+			return fmt.Errorf("unable to read %s: %s", key, err)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if secret.Data == nil {
+			deleted = append(deleted, key)
+		} else {
+			live = append(live, key)
+		}
+		return nil
+	}, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	sort.Strings(live)
+	sort.Strings(deleted)
+	return live, deleted, nil
+}
+
+func underMount(s SecretStore, p string) bool {
+	for _, mount := range s.Mounts() {
+		if strings.HasPrefix(p, mount) && len(p) > len(mount) {
+			return true
+		}
+	}
+	return false
+}
+
+// CopySecrets copies every pair, reading each secret and creating it at
+// its destination, then deletes the originals with every version if move
+// is set and all of them were copied. Only the current version of a KV v2
+// secret is copied, not its history. progress, if not nil, is called after each copy with
+// the number copied so far and the total.
+func CopySecrets(ctx context.Context, s SecretStore, pairs []CopyPair, move bool, progress func(done, total int)) error {
+	for i, pair := range pairs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		secret, err := s.Read(ctx, pair.From)
+		if err != nil {
+			return fmt.Errorf("unable to read %s: %s", pair.From, err)
+		}
+		if secret.Data == nil {
+			return fmt.Errorf("no secret at %s", pair.From)
+		}
+		if err := s.Write(pair.To, nil, secret.Data); err != nil {
+			return fmt.Errorf("unable to write %s: %s", pair.To, err)
+		}
+		if progress != nil {
+			progress(i+1, len(pairs))
+		}
+	}
+
+	if !move {
+		return nil
+	}
+	for _, pair := range pairs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := s.DeleteAll(pair.From); err != nil {
+			return fmt.Errorf("copied everything but unable to delete %s: %s", pair.From, err)
+		}
+	}
+	return nil
+}
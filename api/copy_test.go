@@ -0,0 +1,87 @@
+package api
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestCopySecrets(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore("kv1/", "secret/")
+	s.Write("kv1/app/db", nil, map[string]interface{}{"user": "app"})
+	s.Write("kv1/app/prod/token", nil, map[string]interface{}{"value": "t"})
+	s.Write("secret/taken/db", nil, map[string]interface{}{"user": "other"})
+
+	tests := []struct {
+		src, dst string
+		expected []CopyPair
+	}{
+		{"kv1/app/db", "secret/db", []CopyPair{{"kv1/app/db", "secret/db"}}},
+		{"kv1/app/db", "secret/new/", []CopyPair{{"kv1/app/db", "secret/new/db"}}},
+		{"kv1/app/", "secret/app", []CopyPair{{"kv1/app/db", "secret/app/db"}, {"kv1/app/prod/token", "secret/app/prod/token"}}},
+		{"kv1/app/db", "newname", []CopyPair{{"kv1/app/db", "kv1/app/newname"}}},
+		{"kv1/app/prod/", "staging", []CopyPair{{"kv1/app/prod/token", "kv1/app/staging/token"}}},
+	}
+	for _, test := range tests {
+		pairs, _, err := CopyPairs(ctx, s, test.src, test.dst)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(pairs, test.expected) {
+			t.Errorf("Test failed, expected: '%v', got:  '%v'", test.expected, pairs)
+		}
+	}
+
+	for _, dst := range []string{"secret/taken/", "kv1/app/copy/", "kv1/app/"} {
+		if _, _, err := CopyPairs(ctx, s, "kv1/app/", dst); err == nil {
+			t.Errorf("Test failed, expected copying kv1/app/ to %s to be refused", dst)
+		}
+	}
+
+	for _, dst := range []string{"other/db", "/db"} {
+		if _, _, err := CopyPairs(ctx, s, "kv1/app/db", dst); err == nil {
+			t.Errorf("Test failed, expected copying kv1/app/db to %s to be refused", dst)
+		}
+	}
+
+	pairs, _, _ := CopyPairs(ctx, s, "kv1/app/", "secret/app/")
+	if err := CopySecrets(ctx, s, pairs, true, nil); err != nil {
+		t.Fatal(err)
+	}
+	moved, _ := ListAllKeys(ctx, s, "secret/app/", nil)
+	left, _ := ListAllKeys(ctx, s, "kv1/", nil)
+	if !reflect.DeepEqual(moved, []string{"secret/app/db", "secret/app/prod/token"}) || len(left) != 0 {
+		t.Errorf("Test failed, expected everything moved, got:  '%v' and '%v' left", moved, left)
+	}
+	secret, _ := s.Read(ctx, "secret/app/prod/token")
+	if secret.Data["value"] != "t" {
+		t.Errorf("Test failed, expected: 'map[value:t]', got:  '%v'", secret.Data)
+	}
+}
+
+func TestCopySecretsKVv2(t *testing.T) {
+	ctx := context.Background()
+	srv := useKVv2(t)
+	srv.data["app/db"] = map[string]interface{}{"user": "app"}
+	srv.data["app/old"] = map[string]interface{}{"user": "old"}
+	srv.data["app/prod/token"] = map[string]interface{}{"value": "t"}
+	srv.deleted["app/old"] = true
+
+	pairs, skipped, err := CopyPairs(ctx, Vault, "secret/app/", "secret/moved/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []CopyPair{{"secret/app/db", "secret/moved/db"}, {"secret/app/prod/token", "secret/moved/prod/token"}}
+	if !reflect.DeepEqual(pairs, expected) || !reflect.DeepEqual(skipped, []string{"secret/app/old"}) {
+		t.Errorf("Test failed, expected: '%v' skipping secret/app/old, got:  '%v' skipping %v", expected, pairs, skipped)
+	}
+
+	if err := CopySecrets(ctx, Vault, pairs, true, nil); err != nil {
+		t.Fatal(err)
+	}
+	left, _ := ListAllKeys(ctx, Vault, "secret/", nil)
+	if !reflect.DeepEqual(left, []string{"secret/app/old", "secret/moved/db", "secret/moved/prod/token"}) {
+		t.Errorf("Test failed, expected only the deleted secret left under secret/app/, got:  '%v'", left)
+	}
+}
@@ -0,0 +1,22 @@
+package api
+
+import (
+	"context"
+	"fmt"
+)
+
+// deleteworkers bounds how many secrets DeleteSecrets deletes at once.
+var deleteworkers = 8
+
+// DeleteSecrets deletes the secrets of s at paths with every version, with
+// a bounded pool of concurrent deletes, stopping at the first that fails. It returns how
+// many were deleted. progress, if not nil, is called with the number of
+// secrets deleted so far and the total.
+func DeleteSecrets(ctx context.Context, s SecretStore, paths []string, progress func(done, total int)) (int, error) {
+	return ForEach(ctx, paths, deleteworkers, func(ctx context.Context, path string) error {
+		if err := s.DeleteAll(path); err != nil {
+			return fmt.Errorf("unable to delete %s: %s", path, err)
+		}
+		return nil
+	}, progress)
+}
@@ -0,0 +1,149 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestDeleteSecrets(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore("secret/")
+	for i := 0; i < 20; i++ {
+		s.Write(fmt.Sprintf("secret/app/%02d", i), nil, map[string]interface{}{"n": i})
+	}
+	s.Write("secret/web", nil, map[string]interface{}{"user": "web"})
+
+	keys, _ := ListAllKeys(ctx, s, "secret/app/", nil)
+	var last int
+	deleted, err := DeleteSecrets(ctx, s, keys, func(done, total int) {
+		last = done
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted != 20 || last != 20 {
+		t.Errorf("Test failed, expected: '20', got:  '%d' with progress at %d", deleted, last)
+	}
+
+	left, _ := ListAllKeys(ctx, s, "secret/", nil)
+	if len(left) != 1 || left[0] != "secret/web" {
+		t.Errorf("Test failed, expected: '[secret/web]', got:  '%v'", left)
+	}
+}
+
+// kv2Server serves the secrets in data as a KV v2 mount at secret/.
+// Deleting through data/ only marks a secret deleted, it is still listed
+// until its metadata is deleted.
+type kv2Server struct {
+	mu      sync.Mutex
+	data    map[string]map[string]interface{}
+	deleted map[string]bool
+}
+
+func (s *kv2Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r.URL.Path == "/v1/sys/mounts" {
+		reply(w, 200, map[string]interface{}{"data": map[string]interface{}{
+			"secret/": map[string]interface{}{"type": "kv", "options": map[string]interface{}{"version": "2"}},
+		}})
+		return
+	}
+	p := strings.TrimPrefix(r.URL.Path, "/v1/secret/")
+	if p == "metadata" {
+		p = "metadata/"
+	}
+	switch {
+	case strings.HasPrefix(p, "metadata/") && (r.Method == "LIST" || r.URL.Query().Get("list") == "true"):
+		folder := strings.TrimPrefix(p, "metadata/")
+		if folder != "" && !strings.HasSuffix(folder, "/") {
+			folder += "/"
+		}
+		seen := map[string]bool{}
+		var keys []string
+		for key := range s.data {
+			if !strings.HasPrefix(key, folder) {
+				continue
+			}
+			entry := strings.TrimPrefix(key, folder)
+			if i := strings.Index(entry, "/"); i >= 0 {
+				entry = entry[:i+1]
+			}
+			if !seen[entry] {
+				seen[entry] = true
+				keys = append(keys, entry)
+			}
+		}
+		if len(keys) == 0 {
+			reply(w, 404, map[string]interface{}{"errors": []string{}})
+			return
+		}
+		reply(w, 200, map[string]interface{}{"data": map[string]interface{}{"keys": keys}})
+	case strings.HasPrefix(p, "metadata/") && r.Method == http.MethodDelete:
+		delete(s.data, strings.TrimPrefix(p, "metadata/"))
+		w.WriteHeader(204)
+	case strings.HasPrefix(p, "data/") && r.Method == http.MethodDelete:
+		s.deleted[strings.TrimPrefix(p, "data/")] = true
+		w.WriteHeader(204)
+	case strings.HasPrefix(p, "data/") && r.Method == http.MethodGet:
+		key := strings.TrimPrefix(p, "data/")
+		fields, ok := s.data[key]
+		if !ok {
+			reply(w, 404, map[string]interface{}{"errors": []string{}})
+			return
+		}
+		if s.deleted[key] {
+			// A deleted version is read as a 404 with its metadata.
+			reply(w, 404, map[string]interface{}{"data": map[string]interface{}{"data": nil, "metadata": map[string]interface{}{"version": 1}}})
+			return
+		}
+		reply(w, 200, map[string]interface{}{"data": map[string]interface{}{"data": fields, "metadata": map[string]interface{}{"version": 1}}})
+	case strings.HasPrefix(p, "data/") && (r.Method == http.MethodPost || r.Method == http.MethodPut):
+		var body struct{ Data map[string]interface{} }
+		json.NewDecoder(r.Body).Decode(&body)
+		key := strings.TrimPrefix(p, "data/")
+		s.data[key] = body.Data
+		delete(s.deleted, key)
+		reply(w, 200, map[string]interface{}{"data": map[string]interface{}{"version": 1}})
+	default:
+		reply(w, 405, map[string]interface{}{"errors": []string{"unsupported"}})
+	}
+}
+
+func useKVv2(t *testing.T) *kv2Server {
+	srv := &kv2Server{data: map[string]map[string]interface{}{}, deleted: map[string]bool{}}
+	fakeVault(t, srv)
+	setMounts(map[string]mountInfo{"secret/": {path: "secret/", version: 2}})
+	clearCache()
+	t.Cleanup(func() {
+		setMounts(map[string]mountInfo{})
+		clearCache()
+	})
+	return srv
+}
+
+func TestDeleteSecretsKVv2(t *testing.T) {
+	ctx := context.Background()
+	srv := useKVv2(t)
+	srv.data["app/db"] = map[string]interface{}{"user": "app"}
+	srv.data["app/prod/token"] = map[string]interface{}{"value": "t"}
+	srv.data["web"] = map[string]interface{}{"user": "web"}
+
+	keys, err := ListAllKeys(ctx, Vault, "secret/app/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DeleteSecrets(ctx, Vault, keys, nil); err != nil {
+		t.Fatal(err)
+	}
+	left, _ := ListAllKeys(ctx, Vault, "secret/", nil)
+	if !reflect.DeepEqual(left, []string{"secret/web"}) {
+		t.Errorf("Test failed, expected: '[secret/web]', got:  '%v'", left)
+	}
+}
@@ -0,0 +1,125 @@
+package api
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// listworkers bounds how many list requests ListAllKeys has in flight.
+var listworkers = 16
+
+// listFunc lists the entries directly under a folder, folders ending in "/".
+type listFunc func(ctx context.Context, path string) ([]string, error)
+
+// ListAllKeys walks every folder of s under path with a bounded pool of
+// concurrent list requests and returns the full path of every secret,
+// sorted. progress, if not nil, is called with the number of secrets found
+// so far. The walk stops early when ctx is cancelled.
+func ListAllKeys(ctx context.Context, s SecretStore, path string, progress func(int)) ([]string, error) {
+	return walkKeys(ctx, path, s.List, listworkers, progress)
+}
+
+// ListKeys returns the entries directly under path, sorted, with folders
+// ending in "/".
+func ListKeys(ctx context.Context, path string) ([]string, error) {
+	keys, err := listKeys(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func listKeys(ctx context.Context, path string) ([]string, error) {
+	if keys, ok := cachedList(path); ok {
+		return keys, nil
+	}
+
+	resp, err := client().Logical().ListWithContext(ctx, metadataPath(path))
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	if resp != nil {
+		slice, _ := resp.Data["keys"].([]interface{})
+		keys = make([]string, 0, len(slice))
+		for _, k := range slice {
+			if key, ok := k.(string); ok {
+				keys = append(keys, key)
+			}
+		}
+	}
+	cacheList(path, keys)
+	return keys, nil
+}
+
+func walkKeys(ctx context.Context, root string, list listFunc, workers int, progress func(int)) ([]string, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var mu sync.Mutex
+	var keys []string
+	var firsterr error
+	var pending sync.WaitGroup
+	sem := make(chan struct{}, workers)
+
+	var visit func(folder string)
+	visit = func(folder string) {
+		defer pending.Done()
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			return
+		}
+		entries, err := list(ctx, folder)
+		<-sem
+
+		// Folders the token can't list are skipped, like the vault CLI
+		// does, anything else stops the walk.
+		if err != nil && !(IsForbidden(err) && folder != root) {
+			mu.Lock()
+			if firsterr == nil {
+				firsterr = err
+				cancel()
+			}
+			mu.Unlock()
+			return
+		}
+
+		var found []string
+		for _, entry := range entries {
+			if strings.HasSuffix(entry, "/") {
+				pending.Add(1)
+				go visit(folder + entry)
+			} else {
+				found = append(found, folder+entry)
+			}
+		}
+		if len(found) == 0 {
+			return
+		}
+
+		mu.Lock()
+		keys = append(keys, found...)
+		if progress != nil {
+			progress(len(keys))
+		}
+		mu.Unlock()
+	}
+
+	pending.Add(1)
+	go visit(root)
+	pending.Wait()
+
+	if firsterr != nil {
+		return nil, firsterr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
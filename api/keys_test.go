@@ -0,0 +1,114 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+)
+
+// fakeTree is an in-memory folder tree listed like a KV mount, with an
+// optional delay per request to stand in for network latency.
+type fakeTree struct {
+	folders map[string][]string
+	latency time.Duration
+}
+
+// newFakeTree builds a tree with the given number of folders per level,
+// levels deep, and secrets secrets in every folder.
+func newFakeTree(root string, folders int, depth int, secrets int) *fakeTree {
+	t := &fakeTree{folders: map[string][]string{}}
+	var build func(path string, level int)
+	build = func(path string, level int) {
+		for i := 0; i < secrets; i++ {
+			t.folders[path] = append(t.folders[path], fmt.Sprintf("secret%d", i))
+		}
+		if level == depth {
+			return
+		}
+		for i := 0; i < folders; i++ {
+			name := fmt.Sprintf("folder%d/", i)
+			t.folders[path] = append(t.folders[path], name)
+			build(path+name, level+1)
+		}
+	}
+	build(root, 0)
+	return t
+}
+
+func (t *fakeTree) list(ctx context.Context, path string) ([]string, error) {
+	if t.latency > 0 {
+		select {
+		case <-time.After(t.latency):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return t.folders[path], nil
+}
+
+func (t *fakeTree) secrets() int {
+	n := 0
+	for _, entries := range t.folders {
+		for _, e := range entries {
+			if e[len(e)-1] != '/' {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+func TestWalkKeys(t *testing.T) {
+	tree := newFakeTree("secret/", 3, 3, 4)
+
+	var reported int
+	keys, err := walkKeys(context.Background(), "secret/", tree.list, 4, func(n int) {
+		reported = n
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(keys) != tree.secrets() || reported != len(keys) {
+		t.Errorf("Test failed, expected: '%d', got:  '%d' (progress %d)", tree.secrets(), len(keys), reported)
+	}
+	if !sort.StringsAreSorted(keys) {
+		t.Error("Test failed, expected keys to be sorted")
+	}
+	if keys[0] != "secret/folder0/folder0/folder0/secret0" {
+		t.Errorf("Test failed, expected: 'secret/folder0/folder0/folder0/secret0', got:  '%s'", keys[0])
+	}
+}
+
+func TestWalkKeysCancel(t *testing.T) {
+	tree := newFakeTree("secret/", 4, 4, 2)
+	tree.latency = 10 * time.Millisecond
+
+	ctx, cancel := context.WithTimeout(context.Background(), 25*time.Millisecond)
+	defer cancel()
+
+	_, err := walkKeys(ctx, "secret/", tree.list, 2, nil)
+	if err != context.DeadlineExceeded {
+		t.Errorf("Test failed, expected: '%v', got:  '%v'", context.DeadlineExceeded, err)
+	}
+}
+
+func benchmarkWalkKeys(b *testing.B, workers int) {
+	// About 20,000 secrets in 1,365 folders.
+	tree := newFakeTree("secret/", 4, 5, 15)
+	tree.latency = 200 * time.Microsecond
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := walkKeys(context.Background(), "secret/", tree.list, workers, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkWalkKeysSequential(b *testing.B) { benchmarkWalkKeys(b, 1) }
+func BenchmarkWalkKeys4(b *testing.B)          { benchmarkWalkKeys(b, 4) }
+func BenchmarkWalkKeys16(b *testing.B)         { benchmarkWalkKeys(b, 16) }
+func BenchmarkWalkKeys64(b *testing.B)         { benchmarkWalkKeys(b, 64) }
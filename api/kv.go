@@ -0,0 +1,101 @@
+package api
+
+import (
+	"strings"
+	"sync"
+
+	vault "github.com/hashicorp/vault/api"
+)
+
+// mountInfo records the path and KV version of a mount so logical paths
+// can be translated to the paths the secrets engine expects.
+type mountInfo struct {
+	path    string
+	version int
+}
+
+var mountsmu sync.RWMutex
+var mounts = map[string]mountInfo{}
+
+func kvVersion(t *vault.MountOutput) int {
+	if t.Type == "kv" && t.Options["version"] == "2" {
+		return 2
+	}
+	return 1
+}
+
+func setMounts(m map[string]mountInfo) {
+	mountsmu.Lock()
+	mounts = m
+	mountsmu.Unlock()
+}
+
+// mountFor returns the mount a logical path lives on. Mounts that were not
+// seen by ListMounts are looked up the same way the vault CLI does it.
+func mountFor(path string) (mountInfo, bool) {
+	mountsmu.RLock()
+	var found mountInfo
+	var ok bool
+	for p, m := range mounts {
+		if strings.HasPrefix(path, p) && len(p) > len(found.path) {
+			found, ok = m, true
+		}
+	}
+	mountsmu.RUnlock()
+	if ok {
+		return found, true
+	}
+
+	resp, err := client().Logical().Read("sys/internal/ui/mounts/" + path)
+	if err != nil || resp == nil {
+		return mountInfo{}, false
+	}
+	m := mountInfo{version: 1}
+	m.path, _ = resp.Data["path"].(string)
+	if m.path == "" {
+		return mountInfo{}, false
+	}
+	if opts, ok := resp.Data["options"].(map[string]interface{}); ok && opts["version"] == "2" {
+		m.version = 2
+	}
+
+	mountsmu.Lock()
+	mounts[m.path] = m
+	mountsmu.Unlock()
+	return m, true
+}
+
+// IsKVv2 reports whether path lives on a version 2 KV mount.
+func IsKVv2(path string) bool {
+	m, ok := mountFor(path)
+	return ok && m.version == 2
+}
+
+func kvPath(path string, prefix string) string {
+	m, ok := mountFor(path)
+	if !ok || m.version != 2 {
+		return path
+	}
+	return m.path + prefix + strings.TrimPrefix(path, m.path)
+}
+
+func dataPath(path string) string {
+	return kvPath(path, "data/")
+}
+
+func metadataPath(path string) string {
+	return kvPath(path, "metadata/")
+}
+
+// secretData unwraps the secret fields from a read response. KV v2 nests
+// them under "data" next to the version metadata.
+func secretData(path string, resp *vault.Secret) map[string]interface{} {
+	if resp == nil {
+		return nil
+	}
+	if IsKVv2(path) {
+		data, _ := resp.Data["data"].(map[string]interface{})
+		return data
+	}
+	return resp.Data
+}
@@ -0,0 +1,102 @@
+package api
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// MemoryStore is a SecretStore that keeps secrets in memory, for tests.
+// Every write makes a new version, like a KV v2 mount.
+type MemoryStore struct {
+	mu      sync.Mutex
+	mounts  []string
+	secrets map[string]*Secret
+}
+
+// NewMemoryStore returns an empty store with the given mounts.
+func NewMemoryStore(mounts ...string) *MemoryStore {
+	return &MemoryStore{mounts: mounts, secrets: map[string]*Secret{}}
+}
+
+func (m *MemoryStore) Mounts() []string {
+	return append([]string(nil), m.mounts...)
+}
+
+func (m *MemoryStore) List(ctx context.Context, path string) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	seen := map[string]bool{}
+	for p := range m.secrets {
+		if !strings.HasPrefix(p, path) {
+			continue
+		}
+		entry := p[len(path):]
+		if i := strings.Index(entry, "/"); i >= 0 {
+			entry = entry[:i+1]
+		}
+		seen[entry] = true
+	}
+
+	var keys []string
+	for entry := range seen {
+		keys = append(keys, entry)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func (m *MemoryStore) Read(ctx context.Context, path string) (*Secret, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.secrets[path]
+	if !ok {
+		return &Secret{Path: path}, nil
+	}
+	return &Secret{Path: path, Data: copyData(s.Data), Version: s.Version}, nil
+}
+
+func (m *MemoryStore) Write(path string, original *Secret, data map[string]interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	version, expected := 0, 0
+	if s, ok := m.secrets[path]; ok {
+		version = s.Version
+	}
+	if original != nil {
+		expected = original.Version
+	}
+	if version != expected {
+		return &ConflictError{Path: path, Version: expected}
+	}
+
+	m.secrets[path] = &Secret{Path: path, Data: copyData(data), Version: version + 1}
+	return nil
+}
+
+func (m *MemoryStore) Delete(path string) error {
+	m.mu.Lock()
+	delete(m.secrets, path)
+	m.mu.Unlock()
+	return nil
+}
+
+// DeleteAll is the same as Delete, the store keeps no versions.
+func (m *MemoryStore) DeleteAll(path string) error {
+	return m.Delete(path)
+}
+
+func copyData(data map[string]interface{}) map[string]interface{} {
+	if data == nil {
+		return nil
+	}
+	c := make(map[string]interface{}, len(data))
+	for k, v := range data {
+		c[k] = v
+	}
+	return c
+}
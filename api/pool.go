@@ -0,0 +1,59 @@
+package api
+
+import (
+	"context"
+	"sync"
+)
+
+// ForEach calls work for each of paths with at most workers calls running
+// at once, stopping at the first error work returns. work may run
+// concurrently, so it must guard anything it shares. It returns how many
+// calls succeeded. progress, if not nil, is called with the number of
+// calls done so far and the total.
+func ForEach(ctx context.Context, paths []string, workers int, work func(ctx context.Context, path string) error, progress func(done, total int)) (int, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var mu sync.Mutex
+	var firsterr error
+	var done int
+	var pending sync.WaitGroup
+	sem := make(chan struct{}, workers)
+
+	for _, path := range paths {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		pending.Add(1)
+		go func(path string) {
+			defer pending.Done()
+			err := work(ctx, path)
+			<-sem
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firsterr == nil {
+					firsterr = err
+					cancel()
+				}
+				return
+			}
+			done++
+			if progress != nil {
+				progress(done, len(paths))
+			}
+		}(path)
+	}
+	pending.Wait()
+
+	if firsterr != nil {
+		return done, firsterr
+	}
+	return done, ctx.Err()
+}
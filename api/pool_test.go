@@ -0,0 +1,43 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+)
+
+func TestForEach(t *testing.T) {
+	var paths []string
+	for i := 0; i < 50; i++ {
+		paths = append(paths, fmt.Sprintf("secret/%02d", i))
+	}
+
+	var running, most int32
+	done, err := ForEach(context.Background(), paths, 4, func(ctx context.Context, path string) error {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			m := atomic.LoadInt32(&most)
+			if n <= m || atomic.CompareAndSwapInt32(&most, m, n) {
+				break
+			}
+		}
+		return nil
+	}, nil)
+	if err != nil || done != 50 || most > 4 {
+		t.Errorf("Test failed, expected: '50' with at most 4 running, got:  '%d' with %d running, %v", done, most, err)
+	}
+
+	failed := errors.New("failed")
+	done, err = ForEach(context.Background(), paths, 1, func(ctx context.Context, path string) error {
+		if path == "secret/10" {
+			return failed
+		}
+		return nil
+	}, nil)
+	if err != failed || done != 10 {
+		t.Errorf("Test failed, expected: '10' done and the error, got:  '%d', %v", done, err)
+	}
+}
@@ -0,0 +1,151 @@
+package api
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl"
+	vault "github.com/hashicorp/vault/api"
+)
+
+// Profile is a named Vault connection from the profiles file.
+type Profile struct {
+	Name          string `hcl:"-"`
+	Address       string `hcl:"address"`
+	CACert        string `hcl:"ca_cert"`
+	CAPath        string `hcl:"ca_path"`
+	TLSSkipVerify bool   `hcl:"tls_skip_verify"`
+	Namespace     string `hcl:"namespace"`
+	AuthMethod    string `hcl:"auth_method"`
+	TokenFile     string `hcl:"token_file"`
+}
+
+type profilesConfig struct {
+	Profiles map[string]*Profile `hcl:"profile"`
+}
+
+// profile is the profile in use, nil when the environment is used.
+var profile *Profile
+
+// ProfilesPath returns the location of the profiles file,
+// VAULT_COMMANDER_CONFIG or ~/.vault-commander.hcl.
+func ProfilesPath() (string, error) {
+	if path := os.Getenv("VAULT_COMMANDER_CONFIG"); path != "" {
+		return path, nil
+	}
+	usr, err := user.Current()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(usr.HomeDir, ".vault-commander.hcl"), nil
+}
+
+// LoadProfiles reads the profiles file and returns its profiles sorted by
+// name. A missing file means there are no profiles.
+func LoadProfiles() ([]*Profile, error) {
+	path, err := ProfilesPath()
+	if err != nil {
+		return nil, err
+	}
+
+	contents, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var config profilesConfig
+	if err := hcl.Decode(&config, string(contents)); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %s", path, err)
+	}
+
+	profiles := make([]*Profile, 0, len(config.Profiles))
+	for name, p := range config.Profiles {
+		p.Name = name
+		profiles = append(profiles, p)
+	}
+	sort.Slice(profiles, func(i, j int) bool {
+		return profiles[i].Name < profiles[j].Name
+	})
+	return profiles, nil
+}
+
+// FindProfile returns the profile called name from the profiles file.
+func FindProfile(name string) (*Profile, error) {
+	profiles, err := LoadProfiles()
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range profiles {
+		if p.Name == name {
+			return p, nil
+		}
+	}
+	return nil, fmt.Errorf("no profile named %q", name)
+}
+
+// UseProfile replaces the client with one connected as described by p.
+// Settings the profile leaves empty come from the environment as usual.
+func UseProfile(p *Profile) error {
+	c := vault.DefaultConfig()
+	if c.Error != nil {
+		return c.Error
+	}
+	if p.Address != "" {
+		c.Address = p.Address
+	}
+	if p.CACert != "" || p.CAPath != "" || p.TLSSkipVerify {
+		err := c.ConfigureTLS(&vault.TLSConfig{
+			CACert:   ExpandHome(p.CACert),
+			CAPath:   ExpandHome(p.CAPath),
+			Insecure: p.TLSSkipVerify,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	newcl, err := vault.NewClient(c)
+	if err != nil {
+		return err
+	}
+	if p.Namespace != "" {
+		newcl.SetNamespace(p.Namespace)
+	}
+
+	previous := profile
+	profile = p
+	newcl.ClearToken()
+	if token, err := vaultToken(); err == nil {
+		newcl.SetToken(token)
+	} else if !os.IsNotExist(err) {
+		profile = previous
+		return err
+	}
+
+	setClient(newcl)
+	return nil
+}
+
+// CurrentProfile returns the profile in use, or nil.
+func CurrentProfile() *Profile {
+	return profile
+}
+
+// ExpandHome replaces a leading ~/ in path with the home directory.
+func ExpandHome(path string) string {
+	if !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	usr, err := user.Current()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(usr.HomeDir, path[2:])
+}
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestUseProfile(t *testing.T) {
+	dir := t.TempDir()
+	tokenfile := filepath.Join(dir, "staging-token")
+	if err := ioutil.WriteFile(tokenfile, []byte("s.staging\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	config := filepath.Join(dir, "profiles.hcl")
+	contents := `
+profile "staging" {
+  address     = "https://vault.staging:8200"
+  namespace   = "team"
+  auth_method = "userpass"
+  token_file  = "` + tokenfile + `"
+}
+
+profile "dev" {
+  address = "http://127.0.0.1:8200"
+}
+`
+	if err := ioutil.WriteFile(config, []byte(contents), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("VAULT_COMMANDER_CONFIG", config)
+	t.Setenv("VAULT_TOKEN", "s.env")
+
+	profiles, err := LoadProfiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(profiles) != 2 || profiles[0].Name != "dev" || profiles[1].Name != "staging" {
+		t.Fatalf("Test failed, expected dev and staging profiles, got:  '%v'", profiles)
+	}
+
+	old, oldprofile := cl, profile
+	t.Cleanup(func() {
+		cl, profile = old, oldprofile
+	})
+
+	p, err := FindProfile("staging")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := UseProfile(p); err != nil {
+		t.Fatal(err)
+	}
+
+	if client().Address() != "https://vault.staging:8200" {
+		t.Errorf("Test failed, expected: 'https://vault.staging:8200', got:  '%s'", client().Address())
+	}
+	if client().Namespace() != "team" {
+		t.Errorf("Test failed, expected: 'team', got:  '%s'", client().Namespace())
+	}
+	if client().Token() != "s.staging" {
+		t.Errorf("Test failed, expected: 's.staging', got:  '%s'", client().Token())
+	}
+	if DefaultAuthMethod() != "userpass" {
+		t.Errorf("Test failed, expected: 'userpass', got:  '%s'", DefaultAuthMethod())
+	}
+}
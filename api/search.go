@@ -0,0 +1,91 @@
+package api
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"sync"
+
+	"github.com/rackerlabs/vault-commander/diff"
+)
+
+// searchworkers bounds how many secrets SearchSecrets reads at once.
+var searchworkers = 16
+
+// SearchMatch is a field of a secret whose name or value matched a search.
+// The value itself is left out so it isn't shown by accident.
+type SearchMatch struct {
+	Path    string
+	Field   string
+	InValue bool
+}
+
+// readFunc reads the fields of the secret at path.
+type readFunc func(ctx context.Context, path string) (map[string]interface{}, error)
+
+// SearchSecrets reads every secret of s under prefix with a bounded pool of
+// concurrent reads and returns the fields whose name or value match re,
+// sorted by path and field. progress, if not nil, is called with the number
+// of secrets read so far and the total.
+func SearchSecrets(ctx context.Context, s SecretStore, prefix string, re *regexp.Regexp, progress func(done, total int)) ([]SearchMatch, error) {
+	keys, err := ListAllKeys(ctx, s, prefix, nil)
+	if err != nil {
+		return nil, err
+	}
+	read := func(ctx context.Context, path string) (map[string]interface{}, error) {
+		secret, err := s.Read(ctx, path)
+		if err != nil {
+			return nil, err
+		}
+		return secret.Data, nil
+	}
+	return searchKeys(ctx, keys, read, re, searchworkers, progress)
+}
+
+func searchKeys(ctx context.Context, keys []string, read readFunc, re *regexp.Regexp, workers int, progress func(done, total int)) ([]SearchMatch, error) {
+	var mu sync.Mutex
+	var matches []SearchMatch
+	_, err := ForEach(ctx, keys, workers, func(ctx context.Context, path string) error {
+		data, err := read(ctx, path)
+		// Secrets the token can't read are skipped, anything else stops
+		// the search.
+		if err != nil {
+			if IsForbidden(err) {
+				return nil
+			}
+			return err
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		for field, value := range data {
+			if re.MatchString(field) {
+				matches = append(matches, SearchMatch{Path: path, Field: field})
+			} else if re.MatchString(diff.Value(value, true)) {
+				matches = append(matches, SearchMatch{Path: path, Field: field, InValue: true})
+			}
+		}
+		return nil
+	}, progress)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Path != matches[j].Path {
+			return matches[i].Path < matches[j].Path
+		}
+		return matches[i].Field < matches[j].Field
+	})
+	return matches, nil
+}
+
+// SearchPattern compiles what was typed to search for: text between
+// slashes is a regular expression, anything else is matched as a substring
+// ignoring case.
+func SearchPattern(text string) (*regexp.Regexp, error) {
+	if len(text) > 1 && text[0] == '/' && text[len(text)-1] == '/' {
+		return regexp.Compile(text[1 : len(text)-1])
+	}
+	return regexp.Compile("(?i)" + regexp.QuoteMeta(text))
+}
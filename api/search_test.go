@@ -0,0 +1,53 @@
+package api
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	vault "github.com/hashicorp/vault/api"
+)
+
+func TestSearchKeys(t *testing.T) {
+	secrets := map[string]map[string]interface{}{
+		"secret/app/db":    {"host": "db1.old.example.com", "port": 5432},
+		"secret/app/cache": {"host": "cache.example.com", "old_host": "none"},
+		"secret/web":       {"password": "hunter2"},
+	}
+	read := func(ctx context.Context, path string) (map[string]interface{}, error) {
+		if path == "secret/locked" {
+			return nil, &vault.ResponseError{StatusCode: 403}
+		}
+		return secrets[path], nil
+	}
+	keys := []string{"secret/app/cache", "secret/app/db", "secret/locked", "secret/web"}
+
+	re, err := SearchPattern("OLD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var done, total int
+	matches, err := searchKeys(context.Background(), keys, read, re, 2, func(d, n int) {
+		done, total = d, n
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []SearchMatch{
+		{Path: "secret/app/cache", Field: "old_host"},
+		{Path: "secret/app/db", Field: "host", InValue: true},
+	}
+	if !reflect.DeepEqual(matches, expected) {
+		t.Errorf("Test failed, expected: '%v', got:  '%v'", expected, matches)
+	}
+	if done != 4 || total != 4 {
+		t.Errorf("Test failed, expected: '4/4', got:  '%d/%d'", done, total)
+	}
+
+	re, _ = SearchPattern("/^54\\d+$/")
+	matches, _ = searchKeys(context.Background(), keys, read, re, 2, nil)
+	if len(matches) != 1 || matches[0].Field != "port" {
+		t.Errorf("Test failed, expected a match on port, got:  '%v'", matches)
+	}
+}
@@ -0,0 +1,108 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	vault "github.com/hashicorp/vault/api"
+)
+
+// Secret is the contents of a secret together with the version it was read
+// at. Version is always 0 on KV v1 mounts and for secrets that don't exist.
+type Secret struct {
+	Path    string
+	Data    map[string]interface{}
+	Version int
+}
+
+// String returns the secret contents formatted for display and editing.
+func (s *Secret) String() string {
+	return encodeData(s.Data)
+}
+
+// ConflictError is returned by CheckAndSet when the secret was changed by
+// another writer after it was read.
+type ConflictError struct {
+	Path    string
+	Version int
+}
+
+func (e *ConflictError) Error() string {
+	if e.Version == 0 {
+		return fmt.Sprintf("conflict writing %s: the secret was created or changed by someone else since it was opened", e.Path)
+	}
+	return fmt.Sprintf("conflict writing %s: the secret is no longer at version %d", e.Path, e.Version)
+}
+
+// ReadSecretWithContext reads the current contents and version of a
+// secret, with a context to cancel the request.
+func ReadSecretWithContext(ctx context.Context, path string) (*Secret, error) {
+	resp, err := readSecret(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Secret{Path: path, Data: secretData(path, resp)}
+	if resp != nil && IsKVv2(path) {
+		if md, ok := resp.Data["metadata"].(map[string]interface{}); ok {
+			s.Version = toInt(md["version"])
+		}
+	}
+	return s, nil
+}
+
+// CheckAndSet writes data to path only if the secret still matches
+// original, the secret as it was when it was opened. A nil original means
+// the secret is expected not to exist yet. KV v2 mounts enforce this on the
+// server with the cas option, on KV v1 the current value is compared first.
+func CheckAndSet(path string, original *Secret, data map[string]interface{}) error {
+	// Whatever the outcome the cached value may be stale now, a conflict
+	// above all means the server holds something else.
+	defer invalidate(path)
+	if original == nil {
+		original = &Secret{Path: path}
+	}
+
+	if !IsKVv2(path) {
+		same, err := ComparePathToValue(path, original.Data)
+		if err != nil {
+			return err
+		}
+		if !same {
+			return &ConflictError{Path: path}
+		}
+		return Write(path, data)
+	}
+
+	_, err := client().Logical().Write(dataPath(path), map[string]interface{}{
+		"data":    data,
+		"options": map[string]interface{}{"cas": original.Version},
+	})
+	if isCASMismatch(err) {
+		return &ConflictError{Path: path, Version: original.Version}
+	}
+	return err
+}
+
+func isCASMismatch(err error) bool {
+	re, ok := err.(*vault.ResponseError)
+	if !ok || re.StatusCode != 400 {
+		return false
+	}
+	return strings.Contains(strings.Join(re.Errors, " "), "check-and-set")
+}
+
+func toInt(v interface{}) int {
+	switch n := v.(type) {
+	case json.Number:
+		i, _ := n.Int64()
+		return int(i)
+	case float64:
+		return int(n)
+	case int:
+		return n
+	}
+	return 0
+}
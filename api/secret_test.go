@@ -0,0 +1,114 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+)
+
+// kvServer serves the secrets in data as a KV v1 mount at kv/ and lets
+// them be replaced behind the client's back.
+type kvServer struct {
+	mu   sync.Mutex
+	data map[string]map[string]interface{}
+}
+
+func (s *kvServer) set(path string, fields map[string]interface{}) {
+	s.mu.Lock()
+	s.data[path] = fields
+	s.mu.Unlock()
+}
+
+func (s *kvServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	path := r.URL.Path[len("/v1/"):]
+	fields, ok := s.data[path]
+	if r.Method != http.MethodGet || !ok {
+		reply(w, 404, map[string]interface{}{"errors": []string{}})
+		return
+	}
+	reply(w, 200, map[string]interface{}{"data": fields})
+}
+
+func useKVv1(t *testing.T) *kvServer {
+	srv := &kvServer{data: map[string]map[string]interface{}{}}
+	fakeVault(t, srv)
+	setMounts(map[string]mountInfo{"kv/": {path: "kv/", version: 1}})
+	clearCache()
+	t.Cleanup(func() {
+		setMounts(map[string]mountInfo{})
+		clearCache()
+	})
+	return srv
+}
+
+func TestConflictDropsCachedRead(t *testing.T) {
+	srv := useKVv1(t)
+	srv.set("kv/app/db", map[string]interface{}{"user": "app"})
+
+	original, err := ReadSecretWithContext(context.Background(), "kv/app/db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Someone else changes the secret, the cached read is now stale.
+	srv.set("kv/app/db", map[string]interface{}{"user": "svc"})
+	if cached, _ := ReadSecretWithContext(context.Background(), "kv/app/db"); cached.Data["user"] != "app" {
+		t.Fatalf("Test failed, expected the read to be cached, got:  '%v'", cached.Data)
+	}
+
+	err = CheckAndSet("kv/app/db", original, map[string]interface{}{"user": "mine"})
+	if _, ok := err.(*ConflictError); !ok {
+		t.Fatalf("Test failed, expected a conflict, got:  '%v'", err)
+	}
+	theirs, err := ReadSecretWithContext(context.Background(), "kv/app/db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if theirs.Data["user"] != "svc" {
+		t.Errorf("Test failed, expected: 'map[user:svc]', got:  '%v'", theirs.Data)
+	}
+
+	srv.set("kv/app/db", map[string]interface{}{"user": "other"})
+	if fresh, _ := ReadSecretWithContext(NoCache(context.Background()), "kv/app/db"); fresh.Data["user"] != "other" {
+		t.Errorf("Test failed, expected NoCache to read from Vault, got:  '%v'", fresh.Data)
+	}
+}
+
+func TestCheckAndSetConflict(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/secret/data/app/db", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Data    map[string]interface{}
+			Options struct{ Cas int }
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		switch {
+		case body.Data["user"] == nil:
+			reply(w, 400, map[string]interface{}{"errors": []string{"no data provided"}})
+		case body.Options.Cas != 3:
+			reply(w, 400, map[string]interface{}{"errors": []string{"check-and-set parameter did not match the current version"}})
+		default:
+			reply(w, 200, map[string]interface{}{"data": map[string]interface{}{"version": 4}})
+		}
+	})
+	fakeVault(t, mux)
+	setMounts(map[string]mountInfo{"secret/": {path: "secret/", version: 2}})
+	t.Cleanup(func() { setMounts(map[string]mountInfo{}) })
+
+	data := map[string]interface{}{"user": "app"}
+	err := CheckAndSet("secret/app/db", &Secret{Path: "secret/app/db", Version: 2}, data)
+	if conflict, ok := err.(*ConflictError); !ok || conflict.Version != 2 {
+		t.Errorf("Test failed, expected: 'a conflict at version 2', got:  '%v'", err)
+	}
+	if err := CheckAndSet("secret/app/db", &Secret{Path: "secret/app/db", Version: 3}, data); err != nil {
+		t.Errorf("Test failed, expected: '<nil>', got:  '%v'", err)
+	}
+	err = CheckAndSet("secret/app/db", &Secret{Path: "secret/app/db", Version: 3}, map[string]interface{}{})
+	if _, ok := err.(*ConflictError); ok || err == nil {
+		t.Errorf("Test failed, expected any other 400 to be returned as is, got:  '%v'", err)
+	}
+}
@@ -0,0 +1,54 @@
+package api
+
+import "context"
+
+// SecretStore is where the UI lists, reads, writes and deletes secrets.
+// Vault is the real one, MemoryStore stands in for it in tests.
+type SecretStore interface {
+	// Mounts returns the mounts holding secrets, ending in "/".
+	Mounts() []string
+	// List returns the entries directly under path, folders ending in "/".
+	List(ctx context.Context, path string) ([]string, error)
+	// Read returns the secret at path, with no data if there is none.
+	Read(ctx context.Context, path string) (*Secret, error)
+	// Write stores data at path if the secret is still as original was
+	// read, a nil original meaning it must not exist yet. Otherwise it
+	// returns a *ConflictError.
+	Write(path string, original *Secret, data map[string]interface{}) error
+	// Delete removes the secret at path. On KV v2 only its current
+	// version is deleted and can be undeleted, the secret is still listed.
+	Delete(path string) error
+	// DeleteAll removes the secret at path with every version, so it is no
+	// longer listed.
+	DeleteAll(path string) error
+}
+
+type vaultStore struct{}
+
+// Vault is the store backed by the Vault server the client is connected
+// to.
+var Vault SecretStore = vaultStore{}
+
+func (vaultStore) Mounts() []string {
+	return ListMounts()
+}
+
+func (vaultStore) List(ctx context.Context, path string) ([]string, error) {
+	return ListKeys(ctx, path)
+}
+
+func (vaultStore) Read(ctx context.Context, path string) (*Secret, error) {
+	return ReadSecretWithContext(ctx, path)
+}
+
+func (vaultStore) Write(path string, original *Secret, data map[string]interface{}) error {
+	return CheckAndSet(path, original, data)
+}
+
+func (vaultStore) Delete(path string) error {
+	return Delete(path)
+}
+
+func (vaultStore) DeleteAll(path string) error {
+	return DeleteMetadata(path)
+}
@@ -0,0 +1,151 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/hcl"
+)
+
+// cliConfig is the part of the vault CLI configuration file we use.
+type cliConfig struct {
+	TokenHelper string `hcl:"token_helper"`
+}
+
+// vaultToken finds a token in the same order as the vault CLI: the
+// VAULT_TOKEN environment variable, then the configured token helper, then
+// the token file. A profile with its own token file only uses that file, so
+// a token from the environment is never sent to the wrong cluster.
+func vaultToken() (string, error) {
+	if profile != nil && profile.TokenFile != "" {
+		return fileToken()
+	}
+
+	if token := os.Getenv("VAULT_TOKEN"); token != "" {
+		return token, nil
+	}
+
+	helper, err := tokenHelper()
+	if err != nil {
+		return "", err
+	}
+	if helper != "" {
+		token, err := runTokenHelper(helper, "get", "")
+		if err != nil || token != "" {
+			return token, err
+		}
+	}
+
+	return fileToken()
+}
+
+func tokenPath() (string, error) {
+	if profile != nil && profile.TokenFile != "" {
+		return ExpandHome(profile.TokenFile), nil
+	}
+
+	usr, err := user.Current()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(usr.HomeDir, ".vault-token"), nil
+}
+
+func fileToken() (string, error) {
+	path, err := tokenPath()
+	if err != nil {
+		return "", err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+
+	var vault_token string
+
+	// vault_token file is only one line
+	for scanner.Scan() {
+		vault_token = scanner.Text()
+	}
+
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+
+	return vault_token, nil
+}
+
+// tokenHelper returns the token_helper set in the vault CLI configuration,
+// read from VAULT_CONFIG_PATH or ~/.vault, or "" if there is none.
+func tokenHelper() (string, error) {
+	path := os.Getenv("VAULT_CONFIG_PATH")
+	if path == "" {
+		usr, err := user.Current()
+		if err != nil {
+			return "", err
+		}
+		path = filepath.Join(usr.HomeDir, ".vault")
+	}
+
+	contents, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+
+	var config cliConfig
+	if err := hcl.Decode(&config, string(contents)); err != nil {
+		return "", fmt.Errorf("error parsing %s: %s", path, err)
+	}
+
+	return ExpandHome(config.TokenHelper), nil
+}
+
+// runTokenHelper runs a token helper with the get, store or erase argument
+// and returns what it printed.
+func runTokenHelper(helper string, op string, input string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(helper, op)
+	cmd.Stdin = strings.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("token helper %s %s failed: %s: %s", helper, op, err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// SaveToken stores token through the configured token helper, or in the
+// token file when there is none or the profile names one, so the vault CLI and later sessions pick
+// it up.
+func SaveToken(token string) error {
+	if profile == nil || profile.TokenFile == "" {
+		helper, err := tokenHelper()
+		if err != nil {
+			return err
+		}
+		if helper != "" {
+			_, err := runTokenHelper(helper, "store", token)
+			return err
+		}
+	}
+
+	path, err := tokenPath()
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, []byte(token), 0600)
+}
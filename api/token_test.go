@@ -0,0 +1,65 @@
+package api
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+// writeHelper creates a token helper script and a CLI config using it.
+func writeHelper(t *testing.T, token string) {
+	dir := t.TempDir()
+	helper := filepath.Join(dir, "helper.sh")
+	script := "#!/bin/sh\nif [ \"$1\" = get ]; then echo " + token + "; fi\n"
+	if err := ioutil.WriteFile(helper, []byte(script), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	config := filepath.Join(dir, "vault.hcl")
+	if err := ioutil.WriteFile(config, []byte("token_helper = \""+helper+"\"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("VAULT_CONFIG_PATH", config)
+}
+
+func TestVaultTokenEnvFirst(t *testing.T) {
+	writeHelper(t, "s.helper")
+	t.Setenv("VAULT_TOKEN", "s.env")
+
+	token, err := vaultToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "s.env" {
+		t.Errorf("Test failed, expected: 's.env', got:  '%s'", token)
+	}
+}
+
+func TestVaultTokenHelper(t *testing.T) {
+	writeHelper(t, "s.helper")
+	t.Setenv("VAULT_TOKEN", "")
+
+	token, err := vaultToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "s.helper" {
+		t.Errorf("Test failed, expected: 's.helper', got:  '%s'", token)
+	}
+}
+
+func TestTokenHelperNotConfigured(t *testing.T) {
+	config := filepath.Join(t.TempDir(), "vault.hcl")
+	if err := ioutil.WriteFile(config, []byte("# no helper\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("VAULT_CONFIG_PATH", config)
+
+	helper, err := tokenHelper()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if helper != "" {
+		t.Errorf("Test failed, expected no helper, got:  '%s'", helper)
+	}
+}
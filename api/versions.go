@@ -0,0 +1,124 @@
+package api
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Version describes one entry in the version history of a KV v2 secret.
+type Version struct {
+	Version      int
+	CreatedTime  time.Time
+	DeletionTime time.Time
+	Destroyed    bool
+}
+
+// Deleted reports whether the version has been soft deleted.
+func (v Version) Deleted() bool {
+	return !v.DeletionTime.IsZero()
+}
+
+// ListVersions reads the metadata of a KV v2 secret and returns its
+// versions, newest first.
+func ListVersions(path string) ([]Version, error) {
+	if !IsKVv2(path) {
+		return nil, fmt.Errorf("%s is not on a KV version 2 mount", path)
+	}
+
+	resp, err := client().Logical().Read(metadataPath(path))
+	if err != nil {
+		return nil, err
+	}
+	if resp == nil {
+		return nil, fmt.Errorf("no metadata found for %s", path)
+	}
+
+	raw, _ := resp.Data["versions"].(map[string]interface{})
+	versions := make([]Version, 0, len(raw))
+	for k, val := range raw {
+		n, err := strconv.Atoi(k)
+		if err != nil {
+			continue
+		}
+		m, _ := val.(map[string]interface{})
+		ver := Version{Version: n}
+		ver.CreatedTime = parseTime(m["created_time"])
+		ver.DeletionTime = parseTime(m["deletion_time"])
+		ver.Destroyed, _ = m["destroyed"].(bool)
+		versions = append(versions, ver)
+	}
+
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].Version > versions[j].Version
+	})
+	return versions, nil
+}
+
+// ReadVersion returns the contents of one version of a KV v2 secret
+// formatted the same way as Secret.String.
+func ReadVersion(path string, version int) (string, error) {
+	data, err := readVersion(path, version)
+	if err != nil {
+		return "", err
+	}
+	return encodeData(data), nil
+}
+
+func readVersion(path string, version int) (map[string]interface{}, error) {
+	resp, err := client().Logical().ReadWithData(dataPath(path), map[string][]string{
+		"version": {strconv.Itoa(version)},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	data := secretData(path, resp)
+	if data == nil {
+		return nil, fmt.Errorf("version %d of %s is deleted or destroyed", version, path)
+	}
+	return data, nil
+}
+
+// Rollback writes the contents of an old version back to s as the new
+// current version of a KV v2 secret, if the secret is still as original
+// was read. The contents are returned along with the *ConflictError when
+// it changed, so they can be merged the same way as an edit.
+func Rollback(s SecretStore, path string, original *Secret, version int) (map[string]interface{}, error) {
+	data, err := readVersion(path, version)
+	if err != nil {
+		return nil, err
+	}
+	return data, s.Write(path, original, data)
+}
+
+// Undelete restores soft deleted versions of a KV v2 secret.
+func Undelete(path string, versions []int) error {
+	return versionAction(path, "undelete/", versions)
+}
+
+// Destroy permanently removes the data of versions of a KV v2 secret.
+func Destroy(path string, versions []int) error {
+	return versionAction(path, "destroy/", versions)
+}
+
+func versionAction(path string, prefix string, versions []int) error {
+	if !IsKVv2(path) {
+		return fmt.Errorf("%s is not on a KV version 2 mount", path)
+	}
+	_, err := client().Logical().Write(kvPath(path, prefix), map[string]interface{}{
+		"versions": versions,
+	})
+	invalidate(path)
+	return err
+}
+
+func parseTime(v interface{}) time.Time {
+	s, _ := v.(string)
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
@@ -0,0 +1,110 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// versionsServer serves the metadata of secret/app/db on a KV v2 mount and
+// records the versions posted to undelete and destroy.
+func versionsServer(t *testing.T, posted map[string][]int) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/secret/metadata/app/db", func(w http.ResponseWriter, r *http.Request) {
+		reply(w, 200, map[string]interface{}{"data": map[string]interface{}{
+			"versions": map[string]interface{}{
+				"1": map[string]interface{}{"created_time": "2020-01-01T10:00:00Z", "deletion_time": "", "destroyed": true},
+				"2": map[string]interface{}{"created_time": "2020-01-02T10:00:00Z", "deletion_time": "2020-01-03T10:00:00Z", "destroyed": false},
+				"3": map[string]interface{}{"created_time": "2020-01-04T10:00:00Z", "deletion_time": "", "destroyed": false},
+			},
+		}})
+	})
+	mux.HandleFunc("/v1/secret/data/app/db", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			reply(w, 200, map[string]interface{}{"data": map[string]interface{}{
+				"data":     map[string]interface{}{"user": "v" + r.URL.Query().Get("version")},
+				"metadata": map[string]interface{}{"version": 3},
+			}})
+			return
+		}
+		var body struct{ Options struct{ Cas int } }
+		json.NewDecoder(r.Body).Decode(&body)
+		if body.Options.Cas != 3 {
+			reply(w, 400, map[string]interface{}{"errors": []string{"check-and-set parameter did not match the current version"}})
+			return
+		}
+		reply(w, 200, map[string]interface{}{"data": map[string]interface{}{"version": 4}})
+	})
+	for _, action := range []string{"undelete", "destroy"} {
+		action := action
+		mux.HandleFunc("/v1/secret/"+action+"/app/db", func(w http.ResponseWriter, r *http.Request) {
+			var body struct{ Versions []int }
+			json.NewDecoder(r.Body).Decode(&body)
+			posted[action] = body.Versions
+			w.WriteHeader(204)
+		})
+	}
+
+	fakeVault(t, mux)
+	setMounts(map[string]mountInfo{"secret/": {path: "secret/", version: 2}, "kv/": {path: "kv/", version: 1}})
+	t.Cleanup(func() { setMounts(map[string]mountInfo{}) })
+}
+
+func TestListVersions(t *testing.T) {
+	versionsServer(t, map[string][]int{})
+
+	versions, err := ListVersions("secret/app/db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Version{
+		{Version: 3, CreatedTime: time.Date(2020, 1, 4, 10, 0, 0, 0, time.UTC)},
+		{Version: 2, CreatedTime: time.Date(2020, 1, 2, 10, 0, 0, 0, time.UTC), DeletionTime: time.Date(2020, 1, 3, 10, 0, 0, 0, time.UTC)},
+		{Version: 1, CreatedTime: time.Date(2020, 1, 1, 10, 0, 0, 0, time.UTC), Destroyed: true},
+	}
+	if !reflect.DeepEqual(versions, expected) {
+		t.Errorf("Test failed, expected: '%v', got:  '%v'", expected, versions)
+	}
+	if !versions[1].Deleted() || versions[0].Deleted() {
+		t.Error("Test failed, expected only version 2 to be deleted")
+	}
+
+	if _, err := ListVersions("kv/app/db"); err == nil {
+		t.Error("Test failed, expected versions of a KV v1 secret to be refused")
+	}
+}
+
+func TestUndeleteAndDestroy(t *testing.T) {
+	posted := map[string][]int{}
+	versionsServer(t, posted)
+
+	if err := Undelete("secret/app/db", []int{2}); err != nil {
+		t.Fatal(err)
+	}
+	if err := Destroy("secret/app/db", []int{1, 3}); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string][]int{"undelete": {2}, "destroy": {1, 3}}
+	if !reflect.DeepEqual(posted, expected) {
+		t.Errorf("Test failed, expected: '%v', got:  '%v'", expected, posted)
+	}
+
+	if err := Destroy("kv/app/db", []int{1}); err == nil {
+		t.Error("Test failed, expected destroying on a KV v1 mount to be refused")
+	}
+}
+
+func TestRollback(t *testing.T) {
+	versionsServer(t, map[string][]int{})
+	clearCache()
+
+	data, err := Rollback(Vault, "secret/app/db", &Secret{Path: "secret/app/db", Version: 2}, 1)
+	if _, ok := err.(*ConflictError); !ok || data["user"] != "v1" {
+		t.Errorf("Test failed, expected a conflict with the contents of version 1, got:  '%v', %v", data, err)
+	}
+	if _, err := Rollback(Vault, "secret/app/db", &Secret{Path: "secret/app/db", Version: 3}, 1); err != nil {
+		t.Errorf("Test failed, expected: '<nil>', got:  '%v'", err)
+	}
+}
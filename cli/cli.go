@@ -0,0 +1,443 @@
+// Package cli runs vault-commander subcommands without the UI, for
+// scripts. They go through the same api.SecretStore as the UI, so mounts
+// and KV versions are handled the same way.
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/rackerlabs/vault-commander/api"
+	"github.com/rackerlabs/vault-commander/diff"
+	"github.com/rackerlabs/vault-commander/transfer"
+)
+
+const usage = `usage: vc [flags] <command> [-format json|text] [args]
+
+commands:
+  ls <path>                     list the entries of a folder
+  get <path> [-field f]         print a secret, or one of its fields
+  put <path> k=v... | @file     write a secret from fields or a JSON file
+  rm [-r] <path>                delete a secret, or a folder with every
+                                version of its secrets with -r
+  tree <mount>                  print every secret under a mount
+  export <path> [-o dest]       export every secret under a folder as JSON or
+                                YAML (-format json|yaml), or to a directory
+                                of files when dest ends in /
+  import <file> [path] [-yes]   show what importing a JSON or YAML document,
+                                a directory or a .env file would change, and
+                                write it with -yes; fields are merged into
+                                existing secrets, or replace them with -replace
+`
+
+// Run runs the subcommand in args against s, writing its output to out and
+// status messages that aren't part of it to errout.
+func Run(s api.SecretStore, args []string, out io.Writer, errout io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("no command given\n%s", usage)
+	}
+
+	switch args[0] {
+	case "ls":
+		return ls(s, args[1:], out)
+	case "get":
+		return get(s, args[1:], out)
+	case "put":
+		return put(s, args[1:], out)
+	case "rm":
+		return rm(s, args[1:], out)
+	case "tree":
+		return tree(s, args[1:], out)
+	case "export":
+		return export(s, args[1:], out, errout)
+	case "import":
+		return importSecrets(s, args[1:], out, errout)
+	case "help":
+		fmt.Fprint(out, usage)
+		return nil
+	}
+	return fmt.Errorf("unknown command %q\n%s", args[0], usage)
+}
+
+// newFlags returns the flags of a subcommand with the -format flag every
+// subcommand has.
+func newFlags(name string) (*flag.FlagSet, *string) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	format := fs.String("format", "text", "output format, json or text")
+	return fs, format
+}
+
+// parseArgs parses the flags in args, also when they come after the
+// positional arguments, and returns the positional arguments.
+func parseArgs(fs *flag.FlagSet, format *string, args []string) ([]string, error) {
+	positional, err := parsePositional(fs, args)
+	if err != nil {
+		return nil, err
+	}
+	if *format != "json" && *format != "text" {
+		return nil, fmt.Errorf("%s: unknown format %q, use json or text", fs.Name(), *format)
+	}
+	return positional, nil
+}
+
+// parsePositional parses the flags in args wherever they are and returns
+// the positional arguments.
+func parsePositional(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, fmt.Errorf("%s: %s", fs.Name(), err)
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			break
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+	return positional, nil
+}
+
+func folder(path string) string {
+	if strings.HasSuffix(path, "/") {
+		return path
+	}
+	return path + "/"
+}
+
+func writeJSON(out io.Writer, v interface{}) error {
+	enc := json.NewEncoder(out)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func ls(s api.SecretStore, args []string, out io.Writer) error {
+	fs, format := newFlags("ls")
+	args, err := parseArgs(fs, format, args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		return fmt.Errorf("usage: ls [-format json|text] <path>")
+	}
+
+	keys, err := s.List(context.Background(), folder(args[0]))
+	if err != nil {
+		return err
+	}
+	if *format == "json" {
+		if keys == nil {
+			keys = []string{}
+		}
+		return writeJSON(out, keys)
+	}
+	for _, key := range keys {
+		fmt.Fprintln(out, key)
+	}
+	return nil
+}
+
+func get(s api.SecretStore, args []string, out io.Writer) error {
+	fs, format := newFlags("get")
+	field := fs.String("field", "", "print only this field")
+	args, err := parseArgs(fs, format, args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		return fmt.Errorf("usage: get [-format json|text] [-field f] <path>")
+	}
+
+	secret, err := s.Read(context.Background(), args[0])
+	if err != nil {
+		return err
+	}
+	if secret.Data == nil {
+		return fmt.Errorf("no secret at %s", args[0])
+	}
+
+	if *field != "" {
+		value, ok := secret.Data[*field]
+		if !ok {
+			return fmt.Errorf("%s has no field %q", args[0], *field)
+		}
+		if *format == "json" {
+			return writeJSON(out, value)
+		}
+		fmt.Fprintln(out, diff.Value(value, true))
+		return nil
+	}
+
+	if *format == "json" {
+		return writeJSON(out, secret.Data)
+	}
+	fields := make([]string, 0, len(secret.Data))
+	for f := range secret.Data {
+		fields = append(fields, f)
+	}
+	sort.Strings(fields)
+	for _, f := range fields {
+		fmt.Fprintf(out, "%s=%s\n", f, diff.Value(secret.Data[f], true))
+	}
+	return nil
+}
+
+func put(s api.SecretStore, args []string, out io.Writer) error {
+	fs, format := newFlags("put")
+	args, err := parseArgs(fs, format, args)
+	if err != nil {
+		return err
+	}
+	if len(args) < 2 {
+		return fmt.Errorf("usage: put [-format json|text] <path> k=v... | @file.json")
+	}
+
+	path := args[0]
+	data := map[string]interface{}{}
+	for _, arg := range args[1:] {
+		if strings.HasPrefix(arg, "@") {
+			if err := readJSONFile(arg[1:], data); err != nil {
+				return err
+			}
+			continue
+		}
+		kv := strings.SplitN(arg, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return fmt.Errorf("put: %q is not k=v or @file", arg)
+		}
+		data[kv[0]] = kv[1]
+	}
+
+	current, err := s.Read(context.Background(), path)
+	if err != nil {
+		return err
+	}
+	if err := s.Write(path, current, data); err != nil {
+		return err
+	}
+	return report(out, *format, "written", path)
+}
+
+// readJSONFile adds the fields of the JSON object in the file to data.
+func readJSONFile(name string, data map[string]interface{}) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	dec := json.NewDecoder(f)
+	dec.UseNumber()
+	var fields map[string]interface{}
+	if err := dec.Decode(&fields); err != nil {
+		return fmt.Errorf("error parsing %s: %s", name, err)
+	}
+	for k, v := range fields {
+		data[k] = v
+	}
+	return nil
+}
+
+func rm(s api.SecretStore, args []string, out io.Writer) error {
+	fs, format := newFlags("rm")
+	recursive := fs.Bool("r", false, "delete every secret under the folder")
+	args, err := parseArgs(fs, format, args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		return fmt.Errorf("usage: rm [-format json|text] [-r] <path>")
+	}
+
+	path := args[0]
+	if !*recursive {
+		if strings.HasSuffix(path, "/") {
+			return fmt.Errorf("%s is a folder, use -r to delete everything in it", path)
+		}
+		if err := s.Delete(path); err != nil {
+			return err
+		}
+		return report(out, *format, "deleted", path)
+	}
+
+	keys, err := api.ListAllKeys(context.Background(), s, folder(path), nil)
+	if err != nil {
+		return err
+	}
+	if deleted, err := api.DeleteSecrets(context.Background(), s, keys, nil); err != nil {
+		return fmt.Errorf("deleted %d of %d secrets under %s: %s", deleted, len(keys), folder(path), err)
+	}
+	for _, key := range keys {
+		if err := report(out, *format, "deleted", key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// report prints that action was done to path, as a JSON object per line in
+// json format.
+func report(out io.Writer, format string, action string, path string) error {
+	if format == "json" {
+		b, err := json.Marshal(map[string]string{"action": action, "path": path})
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(out, string(b))
+		return err
+	}
+	verb := "Deleted"
+	if action == "written" {
+		verb = "Wrote"
+	}
+	_, err := fmt.Fprintf(out, "%s %s\n", verb, path)
+	return err
+}
+
+func tree(s api.SecretStore, args []string, out io.Writer) error {
+	fs, format := newFlags("tree")
+	args, err := parseArgs(fs, format, args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		return fmt.Errorf("usage: tree [-format json|text] <mount>")
+	}
+
+	mount := folder(args[0])
+	keys, err := api.ListAllKeys(context.Background(), s, mount, nil)
+	if err != nil {
+		return err
+	}
+
+	if *format == "json" {
+		root := map[string]interface{}{}
+		for _, key := range keys {
+			node := root
+			parts := strings.Split(strings.TrimPrefix(key, mount), "/")
+			for _, part := range parts[:len(parts)-1] {
+				child, ok := node[part+"/"].(map[string]interface{})
+				if !ok {
+					child = map[string]interface{}{}
+					node[part+"/"] = child
+				}
+				node = child
+			}
+			node[parts[len(parts)-1]] = nil
+		}
+		return writeJSON(out, map[string]interface{}{mount: root})
+	}
+
+	fmt.Fprintln(out, mount)
+	printed := map[string]bool{}
+	for _, key := range keys {
+		parts := strings.Split(strings.TrimPrefix(key, mount), "/")
+		for i := range parts[:len(parts)-1] {
+			f := strings.Join(parts[:i+1], "/")
+			if !printed[f] {
+				printed[f] = true
+				fmt.Fprintf(out, "%s%s/\n", strings.Repeat("  ", i+1), parts[i])
+			}
+		}
+		fmt.Fprintf(out, "%s%s\n", strings.Repeat("  ", len(parts)), parts[len(parts)-1])
+	}
+	return nil
+}
+
+func export(s api.SecretStore, args []string, out io.Writer, errout io.Writer) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	format := fs.String("format", "", "output format, json or yaml")
+	dest := fs.String("o", "", "file, or directory ending in /, to write to")
+	args, err := parsePositional(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		return fmt.Errorf("usage: export [-format json|yaml] [-o dest] <path>")
+	}
+	if *format != "" && *format != "json" && *format != "yaml" {
+		return fmt.Errorf("export: unknown format %q, use json or yaml", *format)
+	}
+
+	secrets, err := transfer.Export(context.Background(), s, folder(args[0]), nil)
+	if err != nil {
+		return err
+	}
+	if *dest == "" {
+		if *format == "" {
+			*format = "json"
+		}
+		return transfer.Encode(out, *format, secrets)
+	}
+	if err := transfer.Save(*dest, *format, secrets); err != nil {
+		return err
+	}
+	fmt.Fprintf(errout, "Exported %d secrets to %s\n", len(secrets), *dest)
+	return nil
+}
+
+func importSecrets(s api.SecretStore, args []string, out io.Writer, errout io.Writer) error {
+	fs, format := newFlags("import")
+	replace := fs.Bool("replace", false, "replace existing secrets instead of merging into them")
+	yes := fs.Bool("yes", false, "write the secrets, not only show the plan")
+	args, err := parseArgs(fs, format, args)
+	if err != nil {
+		return err
+	}
+	if len(args) < 1 || len(args) > 2 {
+		return fmt.Errorf("usage: import [-format json|text] [-replace] [-yes] <file> [path]")
+	}
+	target := ""
+	if len(args) == 2 {
+		target = args[1]
+	}
+
+	secrets, err := transfer.Load(args[0], target)
+	if err != nil {
+		return err
+	}
+	plan, err := transfer.Plan(context.Background(), s, secrets, *replace, nil)
+	if err != nil {
+		return err
+	}
+
+	if *format == "json" {
+		items := make([]map[string]interface{}, 0, len(plan))
+		for _, item := range plan {
+			fields := map[string]string{}
+			for _, c := range item.Changes {
+				fields[c.Field] = c.Kind.String()
+			}
+			items = append(items, map[string]interface{}{"path": item.Path, "action": item.Action, "fields": fields})
+		}
+		if err := writeJSON(out, items); err != nil {
+			return err
+		}
+	} else {
+		for _, item := range plan {
+			fmt.Fprintf(out, "%-9s %s\n", item.Action, item.Path)
+			for _, c := range item.Changes {
+				fmt.Fprintf(out, "  %s\n", c.String(false))
+			}
+		}
+		counts := transfer.Count(plan)
+		fmt.Fprintf(out, "%d created, %d changed, %d unchanged\n",
+			counts[transfer.Create], counts[transfer.Change], counts[transfer.Unchanged])
+	}
+
+	if !*yes {
+		fmt.Fprintln(errout, "Nothing written, run again with -yes to import")
+		return nil
+	}
+	return transfer.Apply(context.Background(), s, plan, nil)
+}
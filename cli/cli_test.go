@@ -0,0 +1,130 @@
+package cli
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rackerlabs/vault-commander/api"
+)
+
+func newStore() *api.MemoryStore {
+	s := api.NewMemoryStore("secret/")
+	s.Write("secret/app/db", nil, map[string]interface{}{"user": "app", "port": 5432})
+	s.Write("secret/app/prod/token", nil, map[string]interface{}{"value": "t"})
+	s.Write("secret/web", nil, map[string]interface{}{"user": "web"})
+	return s
+}
+
+func run(t *testing.T, s api.SecretStore, args ...string) string {
+	var out bytes.Buffer
+	if err := Run(s, args, &out, ioutil.Discard); err != nil {
+		t.Fatalf("Test failed, %v returned: '%v'", args, err)
+	}
+	return out.String()
+}
+
+func TestLsAndTree(t *testing.T) {
+	s := newStore()
+
+	tests := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"ls", "secret"}, "app/\nweb\n"},
+		{[]string{"ls", "secret/app/", "-format", "json"}, "[\n  \"db\",\n  \"prod/\"\n]\n"},
+		{[]string{"tree", "secret/"}, "secret/\n  app/\n    db\n    prod/\n      token\n  web\n"},
+		{[]string{"tree", "-format", "json", "secret/app"}, "{\n  \"secret/app/\": {\n    \"db\": null,\n    \"prod/\": {\n      \"token\": null\n    }\n  }\n}\n"},
+		{[]string{"export", "secret/app", "-format", "yaml"}, "secret/app/db:\n  port: 5432\n  user: app\nsecret/app/prod/token:\n  value: t\n"},
+	}
+	for _, test := range tests {
+		if got := run(t, s, test.args...); got != test.expected {
+			t.Errorf("Test failed, expected: '%v', got:  '%v'", test.expected, got)
+		}
+	}
+}
+
+func TestGetAndPut(t *testing.T) {
+	s := newStore()
+
+	if got := run(t, s, "get", "secret/app/db"); got != "port=5432\nuser=app\n" {
+		t.Errorf("Test failed, expected: 'port=5432\nuser=app\n', got:  '%v'", got)
+	}
+	if got := run(t, s, "get", "secret/app/db", "-field", "user"); got != "app\n" {
+		t.Errorf("Test failed, expected: 'app', got:  '%v'", got)
+	}
+
+	dir, err := ioutil.TempDir("", "vault-commander-cli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "db.json")
+	ioutil.WriteFile(file, []byte(`{"user": "svc", "port": 6432}`), 0600)
+
+	run(t, s, "put", "secret/app/db", "@"+file, "host=db1")
+	if got := run(t, s, "get", "-format", "json", "secret/app/db"); got != "{\n  \"host\": \"db1\",\n  \"port\": 6432,\n  \"user\": \"svc\"\n}\n" {
+		t.Errorf("Test failed, expected the file and field to be written, got:  '%v'", got)
+	}
+
+	var out bytes.Buffer
+	if err := Run(s, []string{"get", "secret/missing"}, &out, ioutil.Discard); err == nil {
+		t.Error("Test failed, expected an error for a missing secret")
+	}
+	if err := Run(s, []string{"ls", "-format", "yaml", "secret/"}, &out, ioutil.Discard); err == nil {
+		t.Error("Test failed, expected an error for an unknown format")
+	}
+}
+
+func TestRm(t *testing.T) {
+	s := newStore()
+
+	var out bytes.Buffer
+	if err := Run(s, []string{"rm", "secret/app/"}, &out, ioutil.Discard); err == nil {
+		t.Error("Test failed, expected rm of a folder without -r to fail")
+	}
+
+	if got := run(t, s, "rm", "-r", "secret/app"); got != "Deleted secret/app/db\nDeleted secret/app/prod/token\n" {
+		t.Errorf("Test failed, expected: 'Deleted secret/app/db\nDeleted secret/app/prod/token\n', got:  '%v'", got)
+	}
+	if got := run(t, s, "rm", "-format", "json", "secret/web"); got != "{\"action\":\"deleted\",\"path\":\"secret/web\"}\n" {
+		t.Errorf("Test failed, expected a JSON report, got:  '%v'", got)
+	}
+	if got := run(t, s, "ls", "secret/"); got != "" {
+		t.Errorf("Test failed, expected an empty mount, got:  '%v'", got)
+	}
+}
+
+func TestImport(t *testing.T) {
+	s := newStore()
+	dir, err := ioutil.TempDir("", "vault-commander-cli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	env := filepath.Join(dir, "db.env")
+	ioutil.WriteFile(env, []byte("user=svc\n"), 0600)
+
+	expected := "changed   secret/app/db\n  ~ user = ******** -> ********\n0 created, 1 changed, 0 unchanged\n"
+	if got := run(t, s, "import", env, "secret/app/db"); got != expected {
+		t.Errorf("Test failed, expected: '%v', got:  '%v'", expected, got)
+	}
+	if got := run(t, s, "get", "secret/app/db", "-field", "user"); got != "app\n" {
+		t.Errorf("Test failed, expected the plan to write nothing, got:  '%v'", got)
+	}
+
+	var out, errout bytes.Buffer
+	if err := Run(s, []string{"import", env, "secret/app/db"}, &out, &errout); err != nil {
+		t.Fatal(err)
+	}
+	if errout.String() != "Nothing written, run again with -yes to import\n" {
+		t.Errorf("Test failed, expected: 'Nothing written, run again with -yes to import', got:  '%v'", errout.String())
+	}
+
+	run(t, s, "import", "-yes", "-replace", env, "secret/app/db")
+	if got := run(t, s, "get", "secret/app/db"); got != "user=svc\n" {
+		t.Errorf("Test failed, expected: 'user=svc', got:  '%v'", got)
+	}
+}
@@ -0,0 +1,97 @@
+// Package diff compares the fields of two versions of a secret.
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// Kind is how a field differs between two versions of a secret.
+type Kind int
+
+const (
+	Added Kind = iota
+	Removed
+	Changed
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Added:
+		return "added"
+	case Removed:
+		return "removed"
+	}
+	return "changed"
+}
+
+// Change is a field that differs between two versions of a secret.
+type Change struct {
+	Field string
+	Kind  Kind
+	Old   interface{}
+	New   interface{}
+}
+
+// Fields returns the fields that differ from old to new, sorted by name.
+func Fields(old, new map[string]interface{}) []Change {
+	var changes []Change
+	for field, o := range old {
+		n, ok := new[field]
+		if !ok {
+			changes = append(changes, Change{Field: field, Kind: Removed, Old: o})
+		} else if !Equal(o, n) {
+			changes = append(changes, Change{Field: field, Kind: Changed, Old: o, New: n})
+		}
+	}
+	for field, n := range new {
+		if _, ok := old[field]; !ok {
+			changes = append(changes, Change{Field: field, Kind: Added, New: n})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Field < changes[j].Field
+	})
+	return changes
+}
+
+// Equal compares two field values by their JSON encoding, so numbers read
+// from Vault compare equal to the same numbers parsed from a file.
+func Equal(a, b interface{}) bool {
+	ja, erra := json.Marshal(a)
+	jb, errb := json.Marshal(b)
+	return erra == nil && errb == nil && string(ja) == string(jb)
+}
+
+// Mask is shown in place of a value that is not revealed.
+const Mask = "********"
+
+// Value returns a field value as shown in a diff, a string as is and
+// anything else as JSON, or Mask unless reveal is set.
+func Value(value interface{}, reveal bool) string {
+	if !reveal {
+		return Mask
+	}
+	if s, ok := value.(string); ok {
+		return s
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(b)
+}
+
+// String returns the change as a line of a diff, with the values masked
+// unless reveal is set.
+func (c Change) String(reveal bool) string {
+	switch c.Kind {
+	case Added:
+		return fmt.Sprintf("+ %s = %s", c.Field, Value(c.New, reveal))
+	case Removed:
+		return fmt.Sprintf("- %s = %s", c.Field, Value(c.Old, reveal))
+	}
+	return fmt.Sprintf("~ %s = %s -> %s", c.Field, Value(c.Old, reveal), Value(c.New, reveal))
+}
@@ -0,0 +1,29 @@
+package diff
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestFields(t *testing.T) {
+	old := map[string]interface{}{"user": "app", "pass": "old", "port": json.Number("5432"), "host": "db"}
+	new := map[string]interface{}{"user": "app", "pass": "new", "port": 5432, "tls": true}
+
+	changes := Fields(old, new)
+	expected := []Change{
+		{Field: "host", Kind: Removed, Old: "db"},
+		{Field: "pass", Kind: Changed, Old: "old", New: "new"},
+		{Field: "tls", Kind: Added, New: true},
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Fatalf("Test failed, expected: '%v', got:  '%v'", expected, changes)
+	}
+
+	lines := []string{"- host = db", "~ pass = ******** -> ********", "+ tls = true"}
+	for i, c := range changes {
+		if got := c.String(i != 1); got != lines[i] {
+			t.Errorf("Test failed, expected: '%v', got:  '%v'", lines[i], got)
+		}
+	}
+}
@@ -2,13 +2,19 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"log"
+	"os"
 
 	"github.com/jroimartin/gocui"
+	"github.com/rackerlabs/vault-commander/api"
+	"github.com/rackerlabs/vault-commander/cli"
 	"github.com/rackerlabs/vault-commander/ui"
 )
 
 var mp string
+var pf string
+var ns string
 var sidelegend = "↑ - cursor up\n↓ - cursor down\nTab - switch windows\nRet - select mount"
 var mainlegend = "Tab - switch windows\nRet - view secret\na - add secret\nd - delete secret\nSpace - page down"
 var secretlegend = "e - edit secret\nq - quit view"
@@ -16,11 +22,35 @@ var editlegend = "C-l - Open in $EDITOR\nC-x - quit don't save\nC-s - save"
 
 func init() {
 	flag.StringVar(&mp, "mount", "", "Vault Mount")
+	flag.StringVar(&pf, "profile", "", "Connection profile from ~/.vault-commander.hcl")
+	flag.StringVar(&ns, "namespace", "", "Vault Enterprise namespace")
 }
 
 func main() {
 	flag.Parse()
 
+	if pf != "" {
+		p, err := api.FindProfile(pf)
+		if err != nil {
+			fail(err)
+		}
+		if err := api.UseProfile(p); err != nil {
+			fail(err)
+		}
+	} else if err := api.Connect(); err != nil {
+		fail(err)
+	}
+	if ns != "" {
+		api.SetNamespace(ns)
+	}
+
+	if flag.NArg() > 0 {
+		if err := cli.Run(api.Vault, flag.Args(), os.Stdout, os.Stderr); err != nil {
+			fail(err)
+		}
+		return
+	}
+
 	g, err := gocui.NewGui(gocui.OutputNormal)
 	if err != nil {
 		log.Panicln(err)
@@ -35,9 +65,16 @@ func main() {
 		log.Panicln(err)
 	}
 
-	ui.InitScreen(g, mp)
+	ui.InitScreen(g, api.Vault, mp)
+	ui.WatchToken(g)
 
 	if err := g.MainLoop(); err != nil && err != gocui.ErrQuit {
 		log.Panicln(err)
 	}
 }
+
+// fail prints err and exits, for errors the user can fix rather than bugs.
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
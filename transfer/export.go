@@ -0,0 +1,182 @@
+// Package transfer moves secrets between a store and files: a single JSON
+// or YAML document keyed by path, or a directory with a file per secret.
+package transfer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/rackerlabs/vault-commander/api"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// Secrets holds the fields of secrets by their full path.
+type Secrets map[string]map[string]interface{}
+
+// Paths returns the paths of the secrets, sorted.
+func (s Secrets) Paths() []string {
+	paths := make([]string, 0, len(s))
+	for path := range s {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// exportworkers bounds how many secrets Export reads at once.
+var exportworkers = 16
+
+// Export reads every secret of s under prefix with a bounded pool of
+// concurrent reads. progress, if not nil, is called with the number of
+// secrets read so far and the total.
+func Export(ctx context.Context, s api.SecretStore, prefix string, progress func(done, total int)) (Secrets, error) {
+	keys, err := api.ListAllKeys(ctx, s, prefix, nil)
+	if err != nil {
+		return nil, err
+	}
+	read, err := readAll(ctx, s, keys, progress)
+	if err != nil {
+		return nil, err
+	}
+
+	secrets := Secrets{}
+	for path, secret := range read {
+		if secret.Data != nil {
+			secrets[path] = secret.Data
+		}
+	}
+	return secrets, nil
+}
+
+// readAll reads the secrets at paths, exportworkers at a time, stopping at
+// the first error.
+func readAll(ctx context.Context, s api.SecretStore, paths []string, progress func(done, total int)) (map[string]*api.Secret, error) {
+	var mu sync.Mutex
+	read := make(map[string]*api.Secret, len(paths))
+	_, err := api.ForEach(ctx, paths, exportworkers, func(ctx context.Context, path string) error {
+		secret, err := s.Read(ctx, path)
+		if err != nil {
+			return fmt.Errorf("unable to read %s: %s", path, err)
+		}
+		mu.Lock()
+		read[path] = secret
+		mu.Unlock()
+		return nil
+	}, progress)
+	if err != nil {
+		return nil, err
+	}
+	return read, nil
+}
+
+// FormatFor returns the format of a file by its extension, yaml for .yaml
+// and .yml files and json for anything else.
+func FormatFor(name string) string {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".yaml", ".yml":
+		return "yaml"
+	}
+	return "json"
+}
+
+// Encode writes secrets to w as a single document in format, json or yaml.
+func Encode(w io.Writer, format string, secrets Secrets) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		return enc.Encode(secrets)
+	case "yaml":
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(plain(secrets)); err != nil {
+			return err
+		}
+		return enc.Close()
+	}
+	return fmt.Errorf("unknown format %q, use json or yaml", format)
+}
+
+// plain turns the json.Number values Vault returns into numbers the YAML
+// encoder writes as numbers rather than strings.
+func plain(v interface{}) interface{} {
+	switch v := v.(type) {
+	case Secrets:
+		m := make(map[string]interface{}, len(v))
+		for k, fields := range v {
+			m[k] = plain(fields)
+		}
+		return m
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[k] = plain(e)
+		}
+		return m
+	case []interface{}:
+		l := make([]interface{}, len(v))
+		for i, e := range v {
+			l[i] = plain(e)
+		}
+		return l
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		if f, err := v.Float64(); err == nil {
+			return f
+		}
+		return v.String()
+	}
+	return v
+}
+
+// WriteDir writes every secret to its own JSON file under dir, at its path
+// with a .json extension.
+func WriteDir(dir string, secrets Secrets) error {
+	for _, path := range secrets.Paths() {
+		name := filepath.Join(dir, filepath.FromSlash(path)+".json")
+		if err := os.MkdirAll(filepath.Dir(name), 0700); err != nil {
+			return err
+		}
+		b, err := json.MarshalIndent(secrets[path], "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(name, append(b, '\n'), 0600); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Save writes secrets to dest: a directory of files when dest ends in "/",
+// otherwise a single document in format, or in the format the extension
+// of dest implies when format is "".
+func Save(dest string, format string, secrets Secrets) error {
+	if strings.HasSuffix(dest, "/") || strings.HasSuffix(dest, string(filepath.Separator)) {
+		return WriteDir(dest, secrets)
+	}
+	if format == "" {
+		format = FormatFor(dest)
+	}
+
+	f, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if err := Encode(f, format, secrets); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,91 @@
+package transfer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rackerlabs/vault-commander/api"
+)
+
+func newStore() *api.MemoryStore {
+	s := api.NewMemoryStore("secret/")
+	s.Write("secret/app/db", nil, map[string]interface{}{"user": "app", "port": json.Number("5432")})
+	s.Write("secret/app/prod/token", nil, map[string]interface{}{"value": "t"})
+	s.Write("secret/web", nil, map[string]interface{}{"user": "web"})
+	return s
+}
+
+func TestExport(t *testing.T) {
+	var done, total int
+	secrets, err := Export(context.Background(), newStore(), "secret/app/", func(d, n int) {
+		done, total = d, n
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(secrets) != 2 || done != 2 || total != 2 {
+		t.Fatalf("Test failed, expected: '2 secrets', got:  '%v' (%d/%d)", secrets, done, total)
+	}
+
+	var out bytes.Buffer
+	if err := Encode(&out, "json", secrets); err != nil {
+		t.Fatal(err)
+	}
+	expected := `{
+  "secret/app/db": {
+    "port": 5432,
+    "user": "app"
+  },
+  "secret/app/prod/token": {
+    "value": "t"
+  }
+}
+`
+	if out.String() != expected {
+		t.Errorf("Test failed, expected: '%v', got:  '%v'", expected, out.String())
+	}
+
+	out.Reset()
+	if err := Encode(&out, "yaml", secrets); err != nil {
+		t.Fatal(err)
+	}
+	expected = `secret/app/db:
+  port: 5432
+  user: app
+secret/app/prod/token:
+  value: t
+`
+	if out.String() != expected {
+		t.Errorf("Test failed, expected: '%v', got:  '%v'", expected, out.String())
+	}
+}
+
+func TestSaveDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vault-commander-export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	secrets, _ := Export(context.Background(), newStore(), "secret/", nil)
+	if err := Save(dir+"/", "", secrets); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, "secret", "app", "prod", "token.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "{\n  \"value\": \"t\"\n}\n" {
+		t.Errorf("Test failed, expected the secret in its own file, got:  '%s'", b)
+	}
+
+	if FormatFor("dump.YML") != "yaml" || FormatFor("dump") != "json" {
+		t.Error("Test failed, expected formats by extension")
+	}
+}
@@ -0,0 +1,275 @@
+package transfer
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/rackerlabs/vault-commander/api"
+	"github.com/rackerlabs/vault-commander/diff"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// Load reads the secrets to import from name: a .env file, a directory of
+// JSON files as WriteDir writes them, or a JSON or YAML document keyed by
+// path. A .env file holds a single secret and is imported to target. The
+// others keep their paths, unless target is given, in which case the
+// folder their paths share is replaced with target.
+func Load(name string, target string) (Secrets, error) {
+	info, err := os.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var secrets Secrets
+	switch {
+	case info.IsDir():
+		secrets, err = loadDir(name)
+	case isEnv(name):
+		if target == "" || strings.HasSuffix(target, "/") {
+			return nil, fmt.Errorf("%s holds a single secret, give the path of the secret to import it to", name)
+		}
+		fields, err := loadEnv(name)
+		if err != nil {
+			return nil, err
+		}
+		return Secrets{target: fields}, nil
+	default:
+		secrets, err = loadDocument(name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(secrets) == 0 {
+		return nil, fmt.Errorf("no secrets found in %s", name)
+	}
+	if target != "" {
+		secrets = rebase(secrets, target)
+	}
+	return secrets, nil
+}
+
+func isEnv(name string) bool {
+	base := filepath.Base(name)
+	return base == ".env" || strings.HasSuffix(base, ".env")
+}
+
+func loadDocument(name string) (Secrets, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	secrets := Secrets{}
+	if FormatFor(name) == "yaml" {
+		err = yaml.NewDecoder(f).Decode(&secrets)
+	} else {
+		dec := json.NewDecoder(f)
+		dec.UseNumber()
+		err = dec.Decode(&secrets)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error parsing %s: %s", name, err)
+	}
+	return secrets, nil
+}
+
+func loadDir(dir string) (Secrets, error) {
+	secrets := Secrets{}
+	err := filepath.Walk(dir, func(name string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || filepath.Ext(name) != ".json" {
+			return err
+		}
+		rel, err := filepath.Rel(dir, name)
+		if err != nil {
+			return err
+		}
+
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		dec := json.NewDecoder(f)
+		dec.UseNumber()
+		var fields map[string]interface{}
+		if err := dec.Decode(&fields); err != nil {
+			return fmt.Errorf("error parsing %s: %s", name, err)
+		}
+		secrets[strings.TrimSuffix(filepath.ToSlash(rel), ".json")] = fields
+		return nil
+	})
+	return secrets, err
+}
+
+// loadEnv reads the KEY=value lines of a .env file, skipping blank lines
+// and comments.
+func loadEnv(name string) (map[string]interface{}, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	fields := map[string]interface{}{}
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		kv := strings.SplitN(line, "=", 2)
+		key := strings.TrimSpace(kv[0])
+		if len(kv) != 2 || key == "" {
+			return nil, fmt.Errorf("error parsing %s: line %d is not KEY=value", name, n)
+		}
+
+		value := strings.TrimSpace(kv[1])
+		switch {
+		case len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"':
+			unquoted, err := strconv.Unquote(value)
+			if err != nil {
+				return nil, fmt.Errorf("error parsing %s: line %d: %s", name, n, err)
+			}
+			value = unquoted
+		case len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'':
+			value = value[1 : len(value)-1]
+		}
+		fields[key] = value
+	}
+	return fields, scanner.Err()
+}
+
+// rebase moves secrets from the folder their paths share to target.
+func rebase(secrets Secrets, target string) Secrets {
+	if !strings.HasSuffix(target, "/") {
+		target += "/"
+	}
+
+	common := commonFolder(secrets.Paths())
+
+	moved := make(Secrets, len(secrets))
+	for p, fields := range secrets {
+		moved[target+strings.TrimPrefix(p, common)] = fields
+	}
+	return moved
+}
+
+// commonFolder returns the folder all of paths are in, compared a segment
+// at a time, or "" when they share none.
+func commonFolder(paths []string) string {
+	shared := strings.Split(paths[0], "/")
+	shared = shared[:len(shared)-1]
+	for _, p := range paths[1:] {
+		segments := strings.Split(p, "/")
+		segments = segments[:len(segments)-1]
+		n := 0
+		for n < len(shared) && n < len(segments) && shared[n] == segments[n] {
+			n++
+		}
+		shared = shared[:n]
+	}
+	if len(shared) == 0 {
+		return ""
+	}
+	return strings.Join(shared, "/") + "/"
+}
+
+// Action is what importing does to a secret.
+type Action string
+
+const (
+	Create    Action = "created"
+	Change    Action = "changed"
+	Unchanged Action = "unchanged"
+)
+
+// Item is the plan for importing a single secret.
+type Item struct {
+	Path    string
+	Action  Action
+	Changes []diff.Change
+
+	current *api.Secret
+	data    map[string]interface{}
+}
+
+// Plan reads the secrets that importing secrets would write and works out
+// what would change. Imported fields are merged into existing secrets, or
+// replace them entirely if replace is set.
+func Plan(ctx context.Context, s api.SecretStore, secrets Secrets, replace bool, progress func(done, total int)) ([]Item, error) {
+	paths := secrets.Paths()
+	current, err := readAll(ctx, s, paths, progress)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := make([]Item, 0, len(paths))
+	for _, p := range paths {
+		item := Item{Path: p, current: current[p]}
+		old := item.current.Data
+
+		item.data = map[string]interface{}{}
+		if !replace {
+			for k, v := range old {
+				item.data[k] = v
+			}
+		}
+		for k, v := range secrets[p] {
+			item.data[k] = v
+		}
+
+		item.Changes = diff.Fields(old, item.data)
+		switch {
+		case old == nil:
+			item.Action = Create
+		case len(item.Changes) == 0:
+			item.Action = Unchanged
+		default:
+			item.Action = Change
+		}
+		plan = append(plan, item)
+	}
+	return plan, nil
+}
+
+// Count returns how many items of plan have each action.
+func Count(plan []Item) map[Action]int {
+	counts := map[Action]int{}
+	for _, item := range plan {
+		counts[item.Action]++
+	}
+	return counts
+}
+
+// Apply writes the secrets plan creates or changes, checking that none
+// changed since the plan was made, until ctx is cancelled. progress, if
+// not nil, is called after each write with the number written so far and
+// the total.
+func Apply(ctx context.Context, s api.SecretStore, plan []Item, progress func(done, total int)) error {
+	total := len(plan) - Count(plan)[Unchanged]
+	done := 0
+	for _, item := range plan {
+		if item.Action == Unchanged {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := s.Write(item.Path, item.current, item.data); err != nil {
+			return fmt.Errorf("unable to write %s: %s", item.Path, err)
+		}
+		done++
+		if progress != nil {
+			progress(done, total)
+		}
+	}
+	return nil
+}
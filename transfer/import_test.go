@@ -0,0 +1,109 @@
+package transfer
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vault-commander-import")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	env := filepath.Join(dir, "app.env")
+	ioutil.WriteFile(env, []byte("# app\nexport USER=app\nPASS=\"s3\\\"cret\"\nHOST='db'\n"), 0600)
+	secrets, err := Load(env, "secret/app/db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Secrets{"secret/app/db": {"USER": "app", "PASS": `s3"cret`, "HOST": "db"}}
+	if !reflect.DeepEqual(secrets, expected) {
+		t.Errorf("Test failed, expected: '%v', got:  '%v'", expected, secrets)
+	}
+	if _, err := Load(env, ""); err == nil {
+		t.Error("Test failed, expected a .env file without a target to be refused")
+	}
+
+	doc := filepath.Join(dir, "app.yaml")
+	ioutil.WriteFile(doc, []byte("secret/app/db:\n  user: app\nsecret/app/prod/token:\n  value: t\n"), 0600)
+	secrets, err = Load(doc, "other/copy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(secrets.Paths(), []string{"other/copy/db", "other/copy/prod/token"}) {
+		t.Errorf("Test failed, expected the paths moved under other/copy/, got:  '%v'", secrets.Paths())
+	}
+
+	// Paths that share no folder, at the top level or on different mounts,
+	// are moved under target as they are.
+	tests := []struct {
+		secrets  Secrets
+		expected []string
+	}{
+		{Secrets{"db": {}, "web": {}}, []string{"new/db", "new/web"}},
+		{Secrets{"secret/a": {}, "kv/b": {}}, []string{"new/kv/b", "new/secret/a"}},
+		{Secrets{"secret/app/a": {}, "secret/apps/b": {}}, []string{"new/app/a", "new/apps/b"}},
+	}
+	for _, test := range tests {
+		if got := rebase(test.secrets, "new").Paths(); !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("Test failed, expected: '%v', got:  '%v'", test.expected, got)
+		}
+	}
+
+	// A directory written by an export loads back the same secrets.
+	exported, _ := Export(context.Background(), newStore(), "secret/", nil)
+	WriteDir(filepath.Join(dir, "backup"), exported)
+	secrets, err = Load(filepath.Join(dir, "backup"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(secrets.Paths(), exported.Paths()) {
+		t.Errorf("Test failed, expected: '%v', got:  '%v'", exported.Paths(), secrets.Paths())
+	}
+}
+
+func TestPlanAndApply(t *testing.T) {
+	s := newStore()
+	secrets := Secrets{
+		"secret/app/db":    {"user": "svc"},
+		"secret/app/cache": {"host": "redis"},
+		"secret/web":       {"user": "web"},
+	}
+
+	plan, err := Plan(context.Background(), s, secrets, false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actions := map[string]Action{}
+	for _, item := range plan {
+		actions[item.Path] = item.Action
+	}
+	expected := map[string]Action{"secret/app/cache": Create, "secret/app/db": Change, "secret/web": Unchanged}
+	if !reflect.DeepEqual(actions, expected) {
+		t.Errorf("Test failed, expected: '%v', got:  '%v'", expected, actions)
+	}
+
+	if err := Apply(context.Background(), s, plan, nil); err != nil {
+		t.Fatal(err)
+	}
+	secret, _ := s.Read(context.Background(), "secret/app/db")
+	if secret.Data["user"] != "svc" || secret.Data["port"] == nil {
+		t.Errorf("Test failed, expected the fields merged, got:  '%v'", secret.Data)
+	}
+
+	plan, _ = Plan(context.Background(), s, Secrets{"secret/app/db": {"user": "svc"}}, true, nil)
+	if len(plan[0].Changes) != 1 || plan[0].Changes[0].Field != "port" {
+		t.Fatalf("Test failed, expected replacing to remove port, got:  '%v'", plan[0].Changes)
+	}
+	Apply(context.Background(), s, plan, nil)
+	secret, _ = s.Read(context.Background(), "secret/app/db")
+	if !reflect.DeepEqual(secret.Data, map[string]interface{}{"user": "svc"}) {
+		t.Errorf("Test failed, expected: 'map[user:svc]', got:  '%v'", secret.Data)
+	}
+}
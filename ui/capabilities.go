@@ -0,0 +1,148 @@
+package ui
+
+import (
+	"strings"
+
+	"github.com/jroimartin/gocui"
+	"github.com/rackerlabs/vault-commander/api"
+)
+
+// capcache holds the capabilities looked up for paths on the current mount.
+// It is reset when the mount or the token changes.
+var capcache = map[string]api.Capabilities{}
+
+// capspending holds the paths being looked up in the background for the
+// legend. capgen numbers the caches so a lookup made with the previous
+// token doesn't land in the cache of the next.
+var capspending = map[string]bool{}
+var capgen int
+
+// allCapabilities is assumed without an answer, so nothing is hidden and
+// Vault decides.
+var allCapabilities = api.Capabilities{Create: true, Read: true, Update: true, Delete: true, List: true}
+
+func resetCapabilities() {
+	capcache = map[string]api.Capabilities{}
+	capspending = map[string]bool{}
+	capgen++
+}
+
+func capabilities(path string) api.Capabilities {
+	if c, ok := capcache[path]; ok {
+		return c
+	}
+
+	c, err := api.PathCapabilities(path)
+	if err != nil {
+		c = allCapabilities
+	}
+	capcache[path] = c
+	return c
+}
+
+// legendCapabilities returns the capabilities of path if they are known.
+// Otherwise it looks them up in the background, redrawing the main legend
+// once they are, and assumes everything is allowed until then, so moving
+// the cursor never waits on Vault.
+func legendCapabilities(g *gocui.Gui, path string) api.Capabilities {
+	if c, ok := capcache[path]; ok {
+		return c
+	}
+	if capspending[path] {
+		return allCapabilities
+	}
+
+	capspending[path] = true
+	gen := capgen
+	go func() {
+		c, err := api.PathCapabilities(path)
+		if err != nil {
+			c = allCapabilities
+		}
+		g.Execute(func(g *gocui.Gui) error {
+			if gen != capgen {
+				return nil
+			}
+			delete(capspending, path)
+			capcache[path] = c
+			if cv := g.CurrentView(); cv != nil && cv.Name() == "main" {
+				UpdateLegend(g, mainLegend(g))
+			}
+			return nil
+		})
+	}()
+	return allCapabilities
+}
+
+// folderOf returns the folder a new secret next to path would be created
+// in, the current mount if there is no path.
+func folderOf(path string) string {
+	if path == "" {
+		return currentmount
+	}
+	return path[:strings.LastIndex(path, "/")+1]
+}
+
+// mainLegend lists the main view actions the token may use on the path
+// under the cursor.
+func mainLegend(g *gocui.Gui) string {
+	secretpath := mainLine(g)
+	legend := "Tab - switch windows\nRet - open\n→/← - expand/collapse"
+	if legendCapabilities(g, folderOf(secretpath)).Create {
+		legend += "\na - add secret"
+	}
+	if strings.HasSuffix(secretpath, "/") {
+		if legendCapabilities(g, secretpath).Delete {
+			legend += "\nd - delete folder"
+		}
+	} else if secretpath != "" && legendCapabilities(g, secretpath).Delete {
+		legend += "\nd - delete secret"
+	}
+	if secretpath != "" {
+		legend += "\nc/m - copy/move"
+	}
+	if comparemark != nil {
+		legend += "\n= - compare to mark"
+	} else if secretpath != "" && !strings.HasSuffix(secretpath, "/") {
+		legend += "\n= - mark to compare"
+	}
+	legend += "\n/ - filter\ns - search values\nx/i - export/import\nR - refresh"
+	if flatmode {
+		legend += "\nf - tree view"
+	} else {
+		legend += "\nf - flat list"
+	}
+	return legend + "\nSpace - page down\nC-x - cancel listing"
+}
+
+func secretLegend(g *gocui.Gui) string {
+	legend := ""
+	if capabilities(secretPath(g)).Update {
+		legend += "e - edit secret\n"
+	}
+	return legend + "h - version history\nq - quit view"
+}
+
+func MainCursorDown(g *gocui.Gui, v *gocui.View) error {
+	if err := CursorDown(g, v); err != nil {
+		return err
+	}
+	UpdateLegend(g, mainLegend(g))
+	return nil
+}
+
+func MainCursorUp(g *gocui.Gui, v *gocui.View) error {
+	if err := CursorUp(g, v); err != nil {
+		return err
+	}
+	UpdateLegend(g, mainLegend(g))
+	return nil
+}
+
+func MainPageDown(g *gocui.Gui, v *gocui.View) error {
+	if err := PageDown(g, v); err != nil {
+		return err
+	}
+	UpdateLegend(g, mainLegend(g))
+	return nil
+}
@@ -0,0 +1,202 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/jroimartin/gocui"
+	"github.com/rackerlabs/vault-commander/api"
+	"github.com/rackerlabs/vault-commander/diff"
+)
+
+var comparelegend = "↑/↓ - scroll\nv - reveal values\nq - back"
+
+// compareSide is a secret as read for comparing, with the profile it was
+// read from, so a secret marked before switching profiles can be compared
+// with one read after.
+type compareSide struct {
+	label string
+	data  map[string]interface{}
+}
+
+// comparemark is the secret marked to compare the next one with, and
+// compareleft and compareright the two secrets being compared.
+var comparemark *compareSide
+var compareleft, compareright *compareSide
+var comparereveal bool
+
+// profileName returns the name of the profile in use, or "default" for
+// the connection from the environment.
+func profileName() string {
+	if p := api.CurrentProfile(); p != nil {
+		return p.Name
+	}
+	return "default"
+}
+
+func readCompareSide(path string) (*compareSide, error) {
+	secret, err := store.Read(context.Background(), path)
+	if err != nil {
+		return nil, err
+	}
+	if secret.Data == nil {
+		return nil, fmt.Errorf("no secret at %s", path)
+	}
+	return &compareSide{label: profileName() + ":" + path, data: secret.Data}, nil
+}
+
+// MarkCompare marks the secret under the cursor to be compared, or when
+// one is marked already compares it with the secret under the cursor.
+// Marking the marked secret again clears the mark.
+func MarkCompare(g *gocui.Gui, v *gocui.View) error {
+	path := mainLine(g)
+	if path == "" || folderOf(path) == path {
+		return nil
+	}
+
+	side, err := readCompareSide(path)
+	if err != nil {
+		UpdateLog(g, fmt.Sprintf("ERROR: unable to read %s: %s", path, err))
+		return nil
+	}
+
+	switch {
+	case comparemark == nil:
+		comparemark = side
+		UpdateLog(g, fmt.Sprintf("Marked %s, press = on another secret to compare", side.label))
+	case comparemark.label == side.label:
+		comparemark = nil
+		UpdateLog(g, fmt.Sprintf("Unmarked %s", side.label))
+	default:
+		showCompare(g, comparemark, side)
+		return nil
+	}
+	UpdateLegend(g, mainLegend(g))
+	return nil
+}
+
+// showCompare shows left and right side by side.
+func showCompare(g *gocui.Gui, left, right *compareSide) {
+	compareleft, compareright = left, right
+	comparereveal = false
+
+	maxX, maxY := g.Size()
+	CreateView(g, "compareleft", -1, 0, maxX/2, maxY-9)
+	CreateView(g, "compareright", maxX/2, 0, maxX, maxY-9)
+	renderCompare(g)
+	g.SetCurrentView("compareleft")
+	UpdateLegend(g, comparelegend)
+}
+
+// compareRows lines up the fields of left and right, a row per field with
+// a blank row on the side that lacks it. Fields missing on a side are
+// shown in yellow and fields with different values in red. It also
+// returns how many fields differ.
+func compareRows(left, right map[string]interface{}, reveal bool) ([]string, []string, int) {
+	fields := map[string]bool{}
+	for f := range left {
+		fields[f] = true
+	}
+	for f := range right {
+		fields[f] = true
+	}
+	names := make([]string, 0, len(fields))
+	for f := range fields {
+		names = append(names, f)
+	}
+	sort.Strings(names)
+
+	var lrows, rrows []string
+	differ := 0
+	for _, f := range names {
+		l, lok := left[f]
+		r, rok := right[f]
+		color := ""
+		switch {
+		case !lok || !rok:
+			color = "\x1b[33;1m"
+		case !diff.Equal(l, r):
+			color = "\x1b[31;1m"
+		}
+		if color != "" {
+			differ++
+		}
+		lrows = append(lrows, compareRow(f, l, lok, color, reveal))
+		rrows = append(rrows, compareRow(f, r, rok, color, reveal))
+	}
+	return lrows, rrows, differ
+}
+
+func compareRow(field string, value interface{}, set bool, color string, reveal bool) string {
+	if !set {
+		return ""
+	}
+	row := fmt.Sprintf("%s = %s", field, diff.Value(value, reveal))
+	if color == "" {
+		return row
+	}
+	return color + row + "\x1b[0m"
+}
+
+func renderCompare(g *gocui.Gui) {
+	lv, lerr := g.View("compareleft")
+	rv, rerr := g.View("compareright")
+	if lerr != nil || rerr != nil {
+		return
+	}
+
+	lrows, rrows, differ := compareRows(compareleft.data, compareright.data, comparereveal)
+	lv.Clear()
+	rv.Clear()
+	for i := range lrows {
+		fmt.Fprintln(lv, lrows[i])
+		fmt.Fprintln(rv, rrows[i])
+	}
+	lv.Title = compareleft.label
+	rv.Title = fmt.Sprintf("%s (%d of %d fields differ)", compareright.label, differ, len(lrows))
+}
+
+// ScrollCompareUp and ScrollCompareDown scroll both sides together so the
+// fields stay lined up.
+func ScrollCompareUp(g *gocui.Gui, v *gocui.View) error {
+	return scrollCompare(g, -1)
+}
+
+func ScrollCompareDown(g *gocui.Gui, v *gocui.View) error {
+	return scrollCompare(g, 1)
+}
+
+func scrollCompare(g *gocui.Gui, delta int) error {
+	lv, err := g.View("compareleft")
+	if err != nil {
+		return nil
+	}
+	ox, oy := lv.Origin()
+	_, h := lv.Size()
+	oy += delta
+	if oy < 0 || oy+h > len(lv.BufferLines()) {
+		return nil
+	}
+	for _, name := range []string{"compareleft", "compareright"} {
+		if v, err := g.View(name); err == nil {
+			if err := v.SetOrigin(ox, oy); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func RevealCompare(g *gocui.Gui, v *gocui.View) error {
+	comparereveal = !comparereveal
+	renderCompare(g)
+	return nil
+}
+
+func CloseCompare(g *gocui.Gui, v *gocui.View) error {
+	g.DeleteView("compareleft")
+	g.DeleteView("compareright")
+	compareleft, compareright = nil, nil
+	return MainView(g, v)
+}
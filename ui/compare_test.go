@@ -0,0 +1,26 @@
+package ui
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompareRows(t *testing.T) {
+	staging := map[string]interface{}{"user": "app", "host": "db.staging", "debug": true}
+	prod := map[string]interface{}{"user": "app", "host": "db.prod", "replicas": 3}
+
+	left, right, differ := compareRows(staging, prod, true)
+	expectedleft := []string{"\x1b[33;1mdebug = true\x1b[0m", "\x1b[31;1mhost = db.staging\x1b[0m", "", "user = app"}
+	expectedright := []string{"", "\x1b[31;1mhost = db.prod\x1b[0m", "\x1b[33;1mreplicas = 3\x1b[0m", "user = app"}
+	if !reflect.DeepEqual(left, expectedleft) || !reflect.DeepEqual(right, expectedright) {
+		t.Errorf("Test failed, expected: '%q' '%q', got:  '%q' '%q'", expectedleft, expectedright, left, right)
+	}
+	if differ != 3 {
+		t.Errorf("Test failed, expected: '3', got:  '%d'", differ)
+	}
+
+	_, right, _ = compareRows(staging, prod, false)
+	if right[3] != "user = ********" {
+		t.Errorf("Test failed, expected: 'user = ********', got:  '%v'", right[3])
+	}
+}
@@ -0,0 +1,177 @@
+package ui
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jroimartin/gocui"
+	"github.com/rackerlabs/vault-commander/api"
+)
+
+var conflictlegend = "↑/↓ - select field\n← - keep mine\n→ - take theirs\nC-s - save merged\nC-x - back to edit"
+
+// conflictState holds the three versions of a secret being merged after a
+// check-and-set conflict.
+type conflictState struct {
+	path      string
+	base      map[string]interface{}
+	mine      map[string]interface{}
+	theirs    *api.Secret
+	merged    map[string]interface{}
+	conflicts []fieldConflict
+}
+
+var conflict *conflictState
+
+// ResolveConflict opens the conflict view for a save that failed because
+// the secret changed on the server since it was opened.
+func ResolveConflict(g *gocui.Gui, secretpath string, base map[string]interface{}, mine map[string]interface{}) error {
+	c, err := newConflict(secretpath, base, mine)
+	if err != nil {
+		UpdateLog(g, err.Error())
+		return nil
+	}
+	conflict = c
+	theirs, conflicts := c.theirs, c.conflicts
+
+	maxX, maxY := g.Size()
+	paneY := (maxY - 10) * 2 / 3
+	third := (maxX - 2) / 3
+
+	v := CreateView(g, "conflictbase", 1, 1, 1+third, paneY)
+	v.Clear()
+	fmt.Fprintln(v, (&api.Secret{Data: base}).String())
+
+	v = CreateView(g, "conflictmine", 1+third, 1, 1+2*third, paneY)
+	v.Clear()
+	fmt.Fprintln(v, (&api.Secret{Data: mine}).String())
+
+	v = CreateView(g, "conflicttheirs", 1+2*third, 1, maxX-1, paneY)
+	v.Clear()
+	fmt.Fprintln(v, theirs.String())
+
+	v = CreateView(g, "conflictfields", 1, paneY, maxX-1, maxY-10)
+	v.Highlight = true
+	v.SelBgColor = gocui.ColorGreen
+	v.SelFgColor = gocui.ColorBlack
+	renderConflicts(v)
+	if err := v.SetCursor(0, 0); err != nil {
+		return err
+	}
+	if _, err := g.SetCurrentView("conflictfields"); err != nil {
+		return err
+	}
+
+	UpdateLegend(g, conflictlegend)
+	UpdateLog(g, fmt.Sprintf("%s changed on the server while editing, %d field(s) conflict", secretpath, len(conflicts)))
+	return nil
+}
+
+// newConflict reads the secret at path as it is now and merges mine into
+// it, base being the secret as it was before it was edited.
+func newConflict(path string, base map[string]interface{}, mine map[string]interface{}) (*conflictState, error) {
+	theirs, err := store.Read(api.NoCache(context.Background()), path)
+	if err != nil {
+		return nil, err
+	}
+
+	merged, conflicts := threeWayMerge(base, mine, theirs.Data)
+	return &conflictState{
+		path:      path,
+		base:      base,
+		mine:      mine,
+		theirs:    theirs,
+		merged:    merged,
+		conflicts: conflicts,
+	}, nil
+}
+
+// save writes the merge with the conflicting fields resolved as chosen
+// and returns what was written.
+func (c *conflictState) save() (map[string]interface{}, error) {
+	resolved := resolveConflicts(c.merged, c.conflicts)
+	return resolved, store.Write(c.path, c.theirs, resolved)
+}
+
+func KeepMine(g *gocui.Gui, v *gocui.View) error {
+	return pickSide(v, true)
+}
+
+func TakeTheirs(g *gocui.Gui, v *gocui.View) error {
+	return pickSide(v, false)
+}
+
+func pickSide(v *gocui.View, mine bool) error {
+	_, oy := v.Origin()
+	_, cy := v.Cursor()
+	if i := oy + cy; conflict != nil && i < len(conflict.conflicts) {
+		conflict.conflicts[i].useMine = mine
+		renderConflicts(v)
+	}
+	return nil
+}
+
+func SaveMerged(g *gocui.Gui, v *gocui.View) error {
+	if conflict == nil {
+		return nil
+	}
+	resolved, err := conflict.save()
+	if _, ok := err.(*api.ConflictError); ok {
+		UpdateLog(g, fmt.Sprintf("ERROR: %s changed again while merging.", conflict.path))
+		return ResolveConflict(g, conflict.path, conflict.theirs.Data, resolved)
+	} else if err != nil {
+		UpdateLog(g, err.Error())
+		return nil
+	}
+
+	UpdateLog(g, fmt.Sprintf("Wrote merged secret contents to %s", conflict.path))
+	closeConflict(g)
+	return DeletePrompt(g, v)
+}
+
+// CancelConflict returns to the edit view with the local edit intact.
+func CancelConflict(g *gocui.Gui, v *gocui.View) error {
+	closeConflict(g)
+	if _, err := g.SetCurrentView("editsecret"); err != nil {
+		return err
+	}
+	UpdateLegend(g, editlegend)
+	UpdateLog(g, "Merge cancelled, local edit kept")
+	return nil
+}
+
+func closeConflict(g *gocui.Gui) {
+	g.DeleteView("conflictbase")
+	g.DeleteView("conflictmine")
+	g.DeleteView("conflicttheirs")
+	g.DeleteView("conflictfields")
+	conflict = nil
+}
+
+func renderConflicts(v *gocui.View) {
+	v.Clear()
+	if len(conflict.conflicts) == 0 {
+		fmt.Fprintln(v, "No conflicting fields, all changes were merged automatically.")
+		return
+	}
+	for _, c := range conflict.conflicts {
+		side := "theirs"
+		if c.useMine {
+			side = "mine"
+		}
+		fmt.Fprintf(v, "%-24s using %-6s  mine: %s  theirs: %s\n", c.field, side,
+			fieldValue(c.mine, c.mineSet), fieldValue(c.theirs, c.theirsSet))
+	}
+}
+
+func fieldValue(value interface{}, set bool) string {
+	if !set {
+		return "(removed)"
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(b)
+}
@@ -1,26 +1,36 @@
 package ui
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"log"
-	"regexp"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/jroimartin/gocui"
 	"github.com/rackerlabs/vault-commander/api"
+	"github.com/rackerlabs/vault-commander/diff"
 )
 
-var sidelegend = "↑ - cursor up\n↓ - cursor down\nTab - switch windows\nRet - select mount"
-var mainlegend = "Tab - switch windows\nRet - view secret\na - add secret\nd - delete secret\nSpace - page down"
-var secretlegend = "e - edit secret\nq - quit view"
+var sidelegend = "↑ - cursor up\n↓ - cursor down\nTab - switch windows\nRet - select mount\np - switch profile\nn - namespaces"
 var editlegend = "C-l - Open in $EDITOR\nC-x - quit don't save\nC-s - save"
 var editmode string
 
+// opensecret is the secret as it was read when it was opened for viewing,
+// used as the expected state when the edit is saved. secretread is when it
+// was read and secretwarned whether the log already says it is stale.
+var opensecret *api.Secret
+var secretread time.Time
+var secretwarned bool
+
+// store is where secrets are listed, read and written.
+var store api.SecretStore
+
 func NextView(g *gocui.Gui, v *gocui.View) error {
 	if v == nil || v.Name() == "side" {
 		_, err := g.SetCurrentView("main")
-		UpdateLegend(g, mainlegend)
+		UpdateLegend(g, mainLegend(g))
 		return err
 	}
 	_, err := g.SetCurrentView("side")
@@ -32,6 +42,23 @@ func Quit(g *gocui.Gui, v *gocui.View) error {
 	return gocui.ErrQuit
 }
 
+// mainLine returns the path under the cursor of the main view, folders
+// ending in "/".
+func mainLine(g *gocui.Gui) string {
+	if n := selectedNode(g); n != nil {
+		return n.path
+	}
+	return ""
+}
+
+// secretPath returns the path of the secret open in the secret view.
+func secretPath(g *gocui.Gui) string {
+	if opensecret != nil {
+		return opensecret.Path
+	}
+	return mainLine(g)
+}
+
 func GetLine(g *gocui.Gui, v *gocui.View) error {
 	var l string
 	var err error
@@ -45,60 +72,54 @@ func GetLine(g *gocui.Gui, v *gocui.View) error {
 	v.Highlight = true
 	v.SelBgColor = gocui.ColorGreen
 	v.SelFgColor = gocui.ColorBlack
-	v.Clear()
-	var keys []string
-	api.ListAllKeys(keys, l, "", v)
-	if err := v.SetCursor(0, 0); err != nil {
-		return err
-	}
-	if err := v.SetOrigin(0, 0); err != nil {
-		return err
-	}
-	//cx, cy := v.Cursor()
+	resetCapabilities()
+	filtermount = ""
+	currentmount = l
+	UpdateStatus(g)
 
 	g.SetCurrentView("main")
-	UpdateLegend(g, mainlegend)
-	UpdateLog(g, fmt.Sprintf("Viewing secrets on %s mount", l))
+	loadTree(g, l)
+	UpdateLegend(g, mainLegend(g))
 	return nil
 }
 
 func ViewSecret(g *gocui.Gui, v *gocui.View) error {
-	var l string
-	var err error
-
-	_, cy := v.Cursor()
-	if l, err = v.Line(cy); err != nil {
-		l = ""
+	l := mainLine(g)
+	if l == "" || strings.HasSuffix(l, "/") {
+		return nil
 	}
+	return showSecret(g, l)
+}
 
-	if l == "" {
+// showSecret opens the secret at path in the secret view. It is read from
+// Vault rather than the cache, as it is the value an edit starts from.
+func showSecret(g *gocui.Gui, path string) error {
+	secret, err := store.Read(api.NoCache(context.Background()), path)
+	if err != nil {
+		UpdateLog(g, err.Error())
 		return nil
 	}
+	opensecret = secret
+	secretread = time.Now()
+	secretwarned = false
 
 	maxX, maxY := g.Size()
-	v = CreateView(g, "secret", -1, -1, maxX, maxY-9)
-	fmt.Fprintln(v, api.ReadValue(l, v))
-	UpdateLegend(g, secretlegend)
-	UpdateLog(g, fmt.Sprintf("Viewing secret contents of %s", l))
+	v := CreateView(g, "secret", -1, 0, maxX, maxY-9)
+	fmt.Fprintln(v, secret.String())
+	UpdateLegend(g, secretLegend(g))
+	UpdateLog(g, fmt.Sprintf("Viewing secret contents of %s", path))
 	return nil
 }
 
 func DeleteKey(g *gocui.Gui, v *gocui.View) error {
-	var secretpath string
-	var err error
-	x, _ := g.View("main")
-	_, cy := x.Cursor()
-
-	if secretpath, err = x.Line(cy); err != nil {
-		secretpath = ""
-	}
-
-	err = api.Delete(secretpath)
+	secretpath := mainLine(g)
+	err := deleteSecret(secretpath)
 
 	if err != nil {
-		log.Panicln(err)
+		UpdateLog(g, fmt.Sprintf("ERROR: unable to delete %s: %s", secretpath, err))
+	} else {
+		UpdateLog(g, fmt.Sprintf("Deleted secret %s", secretpath))
 	}
-	UpdateLog(g, fmt.Sprintf("Deleted secret %s", secretpath))
 	HomeView(g, v)
 	return nil
 }
@@ -106,7 +127,7 @@ func DeleteKey(g *gocui.Gui, v *gocui.View) error {
 func HomeView(g *gocui.Gui, v *gocui.View) error {
 	views := g.Views()
 	for i := 0; i < len(views); i++ {
-		if views[i].Name() == "side" || views[i].Name() == "main" || views[i].Name() == "log" || views[i].Name() == "legend" {
+		if views[i].Name() == "side" || views[i].Name() == "main" || views[i].Name() == "log" || views[i].Name() == "legend" || views[i].Name() == "status" {
 			continue
 		} else {
 			if err := g.DeleteView(views[i].Name()); err != nil {
@@ -117,7 +138,7 @@ func HomeView(g *gocui.Gui, v *gocui.View) error {
 	if _, err := g.SetCurrentView("main"); err != nil {
 		return err
 	}
-	UpdateLegend(g, mainlegend)
+	UpdateLegend(g, mainLegend(g))
 	v, _ = g.View("side")
 	GetLine(g, v)
 	return nil
@@ -125,14 +146,9 @@ func HomeView(g *gocui.Gui, v *gocui.View) error {
 
 func CancelEdit(g *gocui.Gui, v *gocui.View) error {
 	var secretpath string
-	var err error
 
 	if editmode == "Editing" {
-		x, _ := g.View("main")
-		_, cy := x.Cursor()
-		if secretpath, err = x.Line(cy); err != nil {
-			secretpath = ""
-		}
+		secretpath = secretPath(g)
 	} else if editmode == "Writing" {
 		x, _ := g.View("addkeyprompt")
 		secretpath = x.Buffer()
@@ -145,16 +161,16 @@ func CancelEdit(g *gocui.Gui, v *gocui.View) error {
 }
 
 func DeleteKeyPrompt(g *gocui.Gui, v *gocui.View) error {
-	x, _ := g.View("main")
-	var secretpath string
-	var err error
-
-	_, cy := x.Cursor()
-	if secretpath, err = x.Line(cy); err != nil {
-		secretpath = ""
+	secretpath := mainLine(g)
+	if secretpath == "" {
+		return nil
+	}
+	if strings.HasSuffix(secretpath, "/") {
+		return DeleteFolderPrompt(g, v)
 	}
 
-	if secretpath == "" {
+	if !capabilities(secretpath).Delete {
+		UpdateLog(g, fmt.Sprintf("Permission denied: token can't delete %s", secretpath))
 		return nil
 	}
 
@@ -168,30 +184,19 @@ func DeleteKeyPrompt(g *gocui.Gui, v *gocui.View) error {
 }
 
 func AddKeyPrompt(g *gocui.Gui, v *gocui.View) error {
-	x, _ := g.View("main")
-	var secretpath string
-	var err error
-
-	_, cy := x.Cursor()
-	if secretpath, err = x.Line(cy); err != nil {
-		secretpath = ""
-	}
-
-	if secretpath == "" {
-		v, _ = g.View("side")
-		_, cy := x.Cursor()
-		secretpath, _ = v.Line(cy)
+	folder := folderOf(mainLine(g))
+	if !capabilities(folder).Create {
+		UpdateLog(g, fmt.Sprintf("Permission denied: token can't create secrets in %s", folder))
+		return nil
 	}
-
-	secretpath = regexp.MustCompile("/\\w*$").Split(secretpath, 2)[0]
-	secretlength := len(secretpath) + 19
+	secretlength := len(folder) + 18
 
 	maxX, maxY := g.Size()
 	v = CreateView(g, "addkeyprompt", maxX/2-(secretlength+5)/2, maxY/2, maxX/2+(secretlength+5)/2, maxY/2+2)
 
-	fmt.Fprintln(v, secretpath+"/")
+	fmt.Fprintln(v, folder)
 
-	if err := v.SetCursor(len(secretpath)+1, 0); err != nil {
+	if err := v.SetCursor(len(folder), 0); err != nil {
 		return err
 	}
 
@@ -203,15 +208,16 @@ func EditSecret(g *gocui.Gui, v *gocui.View) error {
 	var err error
 
 	if v.Name() == "secret" {
+		secretpath = secretPath(g)
+		if !capabilities(secretpath).Update {
+			UpdateLog(g, fmt.Sprintf("Permission denied: token can't update %s", secretpath))
+			return nil
+		}
 		editmode = "Editing"
 		secret = v.Buffer()
-		x, _ := g.View("main")
-		_, cy := x.Cursor()
-		if secretpath, err = x.Line(cy); err != nil {
-			secretpath = ""
-		}
 	} else if v.Name() == "addkeyprompt" {
 		editmode = "Writing"
+		opensecret = nil
 		secret = ""
 		x, _ := g.View("addkeyprompt")
 		_, cy := x.Cursor()
@@ -222,7 +228,7 @@ func EditSecret(g *gocui.Gui, v *gocui.View) error {
 	}
 
 	maxX, maxY := g.Size()
-	v = CreateView(g, "editsecret", -1, -1, maxX, maxY-9)
+	v = CreateView(g, "editsecret", -1, 0, maxX, maxY-9)
 	fmt.Fprintln(v, secret)
 	UpdateLegend(g, editlegend)
 	UpdateLog(g, fmt.Sprintf("%s secret contents of %s", editmode, secretpath))
@@ -284,7 +290,7 @@ func CursorUp(g *gocui.Gui, v *gocui.View) error {
 func MainView(g *gocui.Gui, v *gocui.View) error {
 	views := g.Views()
 	for i := 0; i < len(views); i++ {
-		if views[i].Name() == "side" || views[i].Name() == "main" || views[i].Name() == "log" || views[i].Name() == "legend" {
+		if views[i].Name() == "side" || views[i].Name() == "main" || views[i].Name() == "log" || views[i].Name() == "legend" || views[i].Name() == "status" {
 			continue
 		} else {
 			if err := g.DeleteView(views[i].Name()); err != nil {
@@ -295,40 +301,19 @@ func MainView(g *gocui.Gui, v *gocui.View) error {
 	if _, err := g.SetCurrentView("main"); err != nil {
 		return err
 	}
-	UpdateLegend(g, mainlegend)
+	UpdateLegend(g, mainLegend(g))
 	return nil
 }
 
 func SaveSecret(g *gocui.Gui, v *gocui.View) error {
-	x, _ := g.View("main")
-
 	var secretpath, secret string
-	var err error
 
 	v, _ = g.View("editsecret")
 	secret = v.Buffer()
+	secretpath = editPath(g)
 
-	if editmode == "Editing" {
-		_, cy := x.Cursor()
-		if secretpath, err = x.Line(cy); err != nil {
-			secretpath = ""
-		}
-		if !checkForChanges(g, secretpath) {
-			UpdateLog(g, fmt.Sprintf("ERROR: Value of secret changed while editing. Write cancelled."))
-			v, _ := g.View("main")
-			DeletePrompt(g, v)
-			return nil
-		}
-	} else if editmode == "Writing" {
-		x, _ := g.View("addkeyprompt")
-		secretpath = x.Buffer()
-		secretpath = strings.TrimSpace(secretpath)
-	}
-
-	var mdata map[string]interface{}
-
-	err = json.Unmarshal([]byte(secret), &mdata)
-	if err != nil {
+	mdata, err := saveBuffer(secretpath, opensecret, secret)
+	if mdata == nil && err != nil {
 		UpdateLog(g, err.Error())
 		UpdateLog(g, "ERROR: Write cancelled")
 		g.DeleteView("saveprompt")
@@ -339,9 +324,15 @@ func SaveSecret(g *gocui.Gui, v *gocui.View) error {
 		return nil
 	}
 
-	err = api.Write(secretpath, mdata)
-
-	if err != nil {
+	if conflicterr, ok := err.(*api.ConflictError); ok {
+		UpdateLog(g, fmt.Sprintf("ERROR: %s.", conflicterr))
+		g.DeleteView("saveprompt")
+		var base map[string]interface{}
+		if opensecret != nil {
+			base = opensecret.Data
+		}
+		return ResolveConflict(g, secretpath, base, mdata)
+	} else if err != nil {
 		UpdateLog(g, err.Error())
 	} else {
 		UpdateLog(g, fmt.Sprintf("Wrote secret contents to %s", secretpath))
@@ -351,53 +342,115 @@ func SaveSecret(g *gocui.Gui, v *gocui.View) error {
 	return nil
 }
 
+// deleteSecret deletes the secret at path.
+func deleteSecret(path string) error {
+	if path == "" || strings.HasSuffix(path, "/") {
+		return fmt.Errorf("%q is not a secret", path)
+	}
+	return store.Delete(path)
+}
+
+// saveBuffer parses the JSON in buffer and writes it to path if the secret
+// is still as original was read, nil meaning it must not exist yet. The
+// parsed data is returned when the write itself fails, nil data with an
+// error means the buffer isn't valid JSON.
+func saveBuffer(path string, original *api.Secret, buffer string) (map[string]interface{}, error) {
+	var mdata map[string]interface{}
+	if err := json.Unmarshal([]byte(buffer), &mdata); err != nil {
+		return nil, err
+	}
+	return mdata, store.Write(path, original, mdata)
+}
+
 func DeletePrompt(g *gocui.Gui, v *gocui.View) error {
 	g.DeleteView("addkeyprompt")
 	g.DeleteView("saveprompt")
 	g.DeleteView("editsecret")
 	g.DeleteView("secret")
+	g.DeleteView("search")
 	if _, err := g.SetCurrentView("main"); err != nil {
 		return err
 	}
-	UpdateLegend(g, mainlegend)
+	UpdateLegend(g, mainLegend(g))
 	v, _ = g.View("side")
 	GetLine(g, v)
 
 	return nil
 }
 
-func checkForChanges(g *gocui.Gui, secretpath string) bool {
-	secretbuffer, _ := g.View("secret")
-	originalsecret := secretbuffer.Buffer()
-
-	var odata map[string]interface{}
-
-	err := json.Unmarshal([]byte(originalsecret), &odata)
-	if err != nil {
-		fmt.Println("error:", err)
+// editPath returns the path of the secret being edited or written.
+func editPath(g *gocui.Gui) string {
+	if editmode == "Writing" {
+		x, _ := g.View("addkeyprompt")
+		return strings.TrimSpace(x.Buffer())
 	}
-	return api.ComparePathToValue(secretpath, odata)
+	return secretPath(g)
 }
 
+// savechanges are the fields the edit being saved changes, shown with
+// their values masked unless savereveal is set.
+var savechanges []diff.Change
+var savereveal bool
+
+var savelegend = "y - save\nn - discard edit\nv - reveal values"
+
+// SavePrompt shows the fields the edit changes and asks to save them.
 func SavePrompt(g *gocui.Gui, v *gocui.View) error {
-	var secretpath string
-	var err error
+	secretpath := editPath(g)
 
-	x, _ := g.View("main")
-	if editmode == "Editing" {
-		_, cy := x.Cursor()
-		if secretpath, err = x.Line(cy); err != nil {
-			secretpath = ""
+	var mdata map[string]interface{}
+	if err := json.Unmarshal([]byte(v.Buffer()), &mdata); err != nil {
+		UpdateLog(g, fmt.Sprintf("ERROR: invalid JSON: %s", err))
+		return nil
+	}
+	var original map[string]interface{}
+	if opensecret != nil {
+		original = opensecret.Data
+	}
+	savechanges = diff.Fields(original, mdata)
+	savereveal = false
+
+	lines := saveDiff(secretpath, savechanges, savereveal)
+	width := 0
+	for _, line := range lines {
+		if utf8.RuneCountInString(line) > width {
+			width = utf8.RuneCountInString(line)
 		}
-	} else if editmode == "Writing" {
-		x, _ := g.View("addkeyprompt")
-		secretpath = x.Buffer()
-		secretpath = strings.TrimSpace(secretpath)
 	}
-	secretlength := len(secretpath) + 19
-
+	width += 4
+	height := len(lines) + 1
 	maxX, maxY := g.Size()
-	v = CreateView(g, "saveprompt", maxX/2-secretlength/2, maxY/2, maxX/2+secretlength/2, maxY/2+2)
-	fmt.Fprintln(v, "Overwrite "+secretpath+"? (y/n)")
+	if height > maxY-12 {
+		height = maxY - 12
+	}
+	v = CreateView(g, "saveprompt", maxX/2-width/2, maxY/2-height/2, maxX/2+width/2, maxY/2+height/2+1)
+	renderSave(v, secretpath)
+	UpdateLegend(g, savelegend)
+	return nil
+}
+
+// saveDiff returns the lines of the save prompt, the question and the
+// fields the edit adds, removes and changes.
+func saveDiff(path string, changes []diff.Change, reveal bool) []string {
+	lines := []string{"Overwrite " + path + "? (y/n)", ""}
+	if len(changes) == 0 {
+		return append(lines, "No fields changed")
+	}
+	for _, c := range changes {
+		lines = append(lines, c.String(reveal))
+	}
+	return lines
+}
+
+func renderSave(v *gocui.View, path string) {
+	v.Clear()
+	for _, line := range saveDiff(path, savechanges, savereveal) {
+		fmt.Fprintln(v, line)
+	}
+}
+
+func RevealSave(g *gocui.Gui, v *gocui.View) error {
+	savereveal = !savereveal
+	renderSave(v, editPath(g))
 	return nil
 }
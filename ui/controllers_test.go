@@ -0,0 +1,132 @@
+package ui
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/rackerlabs/vault-commander/api"
+	"github.com/rackerlabs/vault-commander/diff"
+)
+
+func useMemoryStore(t *testing.T) *api.MemoryStore {
+	s := api.NewMemoryStore("secret/")
+	previous := store
+	store = s
+	t.Cleanup(func() { store = previous })
+	return s
+}
+
+func TestAddSecret(t *testing.T) {
+	s := useMemoryStore(t)
+
+	if _, err := saveBuffer("secret/app/db", nil, `{"user": "app"}`); err != nil {
+		t.Fatal(err)
+	}
+	secret, _ := s.Read(context.Background(), "secret/app/db")
+	if secret.Data["user"] != "app" || secret.Version != 1 {
+		t.Errorf("Test failed, expected: 'map[user:app]', got:  '%v' at version %d", secret.Data, secret.Version)
+	}
+
+	keys, _ := s.List(context.Background(), "secret/")
+	if !reflect.DeepEqual(keys, []string{"app/"}) {
+		t.Errorf("Test failed, expected: '[app/]', got:  '%v'", keys)
+	}
+
+	// Adding a secret that someone else created in the meantime conflicts.
+	_, err := saveBuffer("secret/app/db", nil, `{"user": "other"}`)
+	if _, ok := err.(*api.ConflictError); !ok {
+		t.Errorf("Test failed, expected a conflict, got:  '%v'", err)
+	}
+}
+
+func TestEditSecret(t *testing.T) {
+	s := useMemoryStore(t)
+	s.Write("secret/app/db", nil, map[string]interface{}{"user": "app"})
+	original, _ := s.Read(context.Background(), "secret/app/db")
+
+	if mdata, err := saveBuffer("secret/app/db", original, `{"user": `); mdata != nil || err == nil {
+		t.Errorf("Test failed, expected invalid JSON to be refused, got:  '%v' '%v'", mdata, err)
+	}
+
+	if _, err := saveBuffer("secret/app/db", original, `{"user": "svc"}`); err != nil {
+		t.Fatal(err)
+	}
+	secret, _ := s.Read(context.Background(), "secret/app/db")
+	if secret.Data["user"] != "svc" || secret.Version != 2 {
+		t.Errorf("Test failed, expected: 'map[user:svc]', got:  '%v' at version %d", secret.Data, secret.Version)
+	}
+}
+
+func TestDeleteSecret(t *testing.T) {
+	s := useMemoryStore(t)
+	s.Write("secret/app/db", nil, map[string]interface{}{"user": "app"})
+	s.Write("secret/web", nil, map[string]interface{}{"user": "web"})
+
+	if err := deleteSecret("secret/app/"); err == nil {
+		t.Error("Test failed, expected deleting a folder to be refused")
+	}
+	if err := deleteSecret("secret/app/db"); err != nil {
+		t.Fatal(err)
+	}
+
+	keys, _ := s.List(context.Background(), "secret/")
+	if !reflect.DeepEqual(keys, []string{"web"}) {
+		t.Errorf("Test failed, expected: '[web]', got:  '%v'", keys)
+	}
+}
+
+func TestConflictFlow(t *testing.T) {
+	s := useMemoryStore(t)
+	s.Write("secret/app/db", nil, map[string]interface{}{"user": "app", "pass": "old"})
+	original, _ := s.Read(context.Background(), "secret/app/db")
+
+	// Someone else changes the secret while it is being edited.
+	theirs, _ := s.Read(context.Background(), "secret/app/db")
+	s.Write("secret/app/db", theirs, map[string]interface{}{"user": "svc", "pass": "theirs"})
+
+	mine, err := saveBuffer("secret/app/db", original, `{"user": "app", "pass": "mine", "port": "5432"}`)
+	if _, ok := err.(*api.ConflictError); !ok {
+		t.Fatalf("Test failed, expected a conflict, got:  '%v'", err)
+	}
+
+	c, err := newConflict("secret/app/db", original.Data, mine)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.conflicts) != 1 || c.conflicts[0].field != "pass" {
+		t.Fatalf("Test failed, expected a single conflict on pass, got:  '%v'", c.conflicts)
+	}
+
+	c.conflicts[0].useMine = true
+	if _, err := c.save(); err != nil {
+		t.Fatal(err)
+	}
+	secret, _ := s.Read(context.Background(), "secret/app/db")
+	expected := map[string]interface{}{"user": "svc", "pass": "mine", "port": "5432"}
+	if !reflect.DeepEqual(secret.Data, expected) {
+		t.Errorf("Test failed, expected: '%v', got:  '%v'", expected, secret.Data)
+	}
+}
+
+func TestSaveDiff(t *testing.T) {
+	original := map[string]interface{}{"user": "app", "pass": "old", "host": "db"}
+	edited := map[string]interface{}{"user": "app", "pass": "new", "port": "5432"}
+
+	got := saveDiff("secret/app/db", diff.Fields(original, edited), false)
+	expected := []string{
+		"Overwrite secret/app/db? (y/n)",
+		"",
+		"- host = ********",
+		"~ pass = ******** -> ********",
+		"+ port = ********",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Test failed, expected: '%v', got:  '%v'", expected, got)
+	}
+
+	got = saveDiff("secret/app/db", diff.Fields(original, edited), true)
+	if got[3] != "~ pass = old -> new" {
+		t.Errorf("Test failed, expected: '~ pass = old -> new', got:  '%v'", got[3])
+	}
+}
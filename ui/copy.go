@@ -0,0 +1,117 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jroimartin/gocui"
+	"github.com/rackerlabs/vault-commander/api"
+)
+
+// copysource is the secret or folder being copied, and copymove whether
+// the originals are deleted once copied.
+var copysource string
+var copymove bool
+
+func CopyPrompt(g *gocui.Gui, v *gocui.View) error {
+	return copyPrompt(g, false)
+}
+
+func MovePrompt(g *gocui.Gui, v *gocui.View) error {
+	return copyPrompt(g, true)
+}
+
+// copyPrompt asks where to copy the secret or folder under the cursor,
+// starting from its own path.
+func copyPrompt(g *gocui.Gui, move bool) error {
+	copysource = mainLine(g)
+	if copysource == "" {
+		return nil
+	}
+	copymove = move
+	verb := "Copy"
+	if move {
+		verb = "Move"
+	}
+
+	length := len(copysource) + 20
+	if length < 50 {
+		length = 50
+	}
+	maxX, maxY := g.Size()
+	v := CreateView(g, "copyprompt", maxX/2-length/2, maxY/2, maxX/2+length/2, maxY/2+2)
+	v.Title = fmt.Sprintf("%s %s To", verb, copysource)
+	v.Clear()
+	fmt.Fprintln(v, copysource)
+	if err := v.SetCursor(len(copysource), 0); err != nil {
+		return err
+	}
+	UpdateLegend(g, fmt.Sprintf("Ret - %s\nC-x - cancel", strings.ToLower(verb)))
+	return nil
+}
+
+// CopyTo copies or moves copysource to the path in the prompt in the
+// background, logging its progress.
+func CopyTo(g *gocui.Gui, v *gocui.View) error {
+	src, move := copysource, copymove
+	dst := api.CopyDestination(src, strings.TrimSpace(v.Buffer()))
+
+	if !capabilities(folderOf(dst)).Create {
+		UpdateLog(g, fmt.Sprintf("Permission denied: token can't create secrets in %s", folderOf(dst)))
+		return nil
+	}
+	if move && !capabilities(src).Delete {
+		UpdateLog(g, fmt.Sprintf("Permission denied: token can't delete %s", src))
+		return nil
+	}
+
+	action, verb, done := "copy", "Copying", "Copied"
+	if move {
+		action, verb, done = "move", "Moving", "Moved"
+	}
+	g.DeleteView("copyprompt")
+	UpdateLog(g, fmt.Sprintf("%s %s to %s", verb, src, dst))
+
+	ctx := jobctx
+	go func() {
+		pairs, skipped, err := api.CopyPairs(ctx, store, src, dst)
+		if len(skipped) > 0 {
+			g.Execute(func(g *gocui.Gui) error {
+				UpdateLog(g, fmt.Sprintf("Skipping %d deleted secrets under %s: %s", len(skipped), src, strings.Join(skipped, ", ")))
+				return nil
+			})
+		}
+		if err == nil {
+			err = api.CopySecrets(ctx, store, pairs, move, func(n, total int) {
+				if total == 1 {
+					return
+				}
+				g.Execute(func(g *gocui.Gui) error {
+					UpdateLog(g, fmt.Sprintf("%s %s: %d of %d written", verb, src, n, total))
+					return nil
+				})
+			})
+		}
+
+		g.Execute(func(g *gocui.Gui) error {
+			if err == context.Canceled {
+				UpdateLog(g, fmt.Sprintf("%s %s stopped, the connection changed", verb, src))
+				return nil
+			}
+			if err != nil {
+				UpdateLog(g, fmt.Sprintf("ERROR: unable to %s %s to %s: %s", action, src, dst, err))
+			} else {
+				UpdateLog(g, fmt.Sprintf("%s %d secrets from %s to %s", done, len(pairs), src, dst))
+			}
+			loadTree(g, currentmount)
+			return nil
+		})
+	}()
+	return MainView(g, v)
+}
+
+func CancelCopyPrompt(g *gocui.Gui, v *gocui.View) error {
+	g.DeleteView("copyprompt")
+	return MainView(g, v)
+}
@@ -0,0 +1,183 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jroimartin/gocui"
+	"github.com/rackerlabs/vault-commander/api"
+)
+
+var deletefolderlegend = "Ret - delete\n↑/↓ - scroll list\nC-x - cancel"
+
+// deletefolder is the folder being deleted and deletekeys the secrets
+// under it, listed before anything is deleted. deletelisted is set once
+// the listing is complete and deleteerr if it failed. deleting numbers the
+// listings so only the latest one fills the view.
+var deletefolder string
+var deletekeys []string
+var deletelisted bool
+var deleteerr error
+var deletecancel context.CancelFunc = func() {}
+var deleting int
+
+// DeleteFolderPrompt lists every secret under the folder under the cursor
+// and asks for the folder path to be typed to confirm deleting them.
+func DeleteFolderPrompt(g *gocui.Gui, v *gocui.View) error {
+	folder := mainLine(g)
+	if !strings.HasSuffix(folder, "/") {
+		return nil
+	}
+	if !capabilities(folder).Delete {
+		UpdateLog(g, fmt.Sprintf("Permission denied: token can't delete secrets in %s", folder))
+		return nil
+	}
+
+	deletecancel()
+	ctx, cancel := context.WithCancel(context.Background())
+	deletecancel = cancel
+	deleting++
+	id := deleting
+	deletefolder = folder
+	deletekeys = nil
+	deletelisted = false
+	deleteerr = nil
+
+	maxX, maxY := g.Size()
+	lv := CreateView(g, "deletefolder", -1, 0, maxX, maxY-12)
+	lv.Clear()
+	lv.SetOrigin(0, 0)
+	lv.Title = fmt.Sprintf("Listing secrets under %s", folder)
+
+	v = CreateView(g, "deleteconfirm", 1, maxY-12, maxX-1, maxY-10)
+	v.Title = fmt.Sprintf("Type %s to delete everything under it with all versions", folder)
+	v.Clear()
+	v.SetCursor(0, 0)
+	g.SetCurrentView("deleteconfirm")
+	UpdateLegend(g, deletefolderlegend)
+
+	go func() {
+		keys, err := api.ListAllKeys(ctx, store, folder, nil)
+		g.Execute(func(g *gocui.Gui) error {
+			if id != deleting {
+				return nil
+			}
+			if err == context.Canceled {
+				return nil
+			}
+			lv, verr := g.View("deletefolder")
+			if err != nil {
+				deleteerr = err
+				UpdateLog(g, fmt.Sprintf("ERROR: unable to list %s: %s", folder, err))
+				if verr == nil {
+					lv.Title = fmt.Sprintf("Unable to list %s", folder)
+					fmt.Fprintln(lv, err)
+				}
+				return nil
+			}
+			deletekeys = keys
+			deletelisted = true
+			if verr != nil {
+				return nil
+			}
+			if len(keys) == 0 {
+				lv.Title = fmt.Sprintf("No secrets under %s", folder)
+				return nil
+			}
+			lv.Title = fmt.Sprintf("%d secrets would be deleted under %s", len(keys), folder)
+			for _, key := range keys {
+				fmt.Fprintln(lv, key)
+			}
+			return nil
+		})
+	}()
+	return nil
+}
+
+// DeleteFolder deletes every secret listed under deletefolder once its
+// path was typed, logging the progress.
+func DeleteFolder(g *gocui.Gui, v *gocui.View) error {
+	switch {
+	case deleteerr != nil:
+		UpdateLog(g, fmt.Sprintf("ERROR: unable to list %s: %s, C-x to cancel", deletefolder, deleteerr))
+		return nil
+	case !deletelisted:
+		UpdateLog(g, fmt.Sprintf("Still listing the secrets under %s", deletefolder))
+		return nil
+	case len(deletekeys) == 0:
+		UpdateLog(g, fmt.Sprintf("No secrets under %s to delete, C-x to cancel", deletefolder))
+		return nil
+	}
+	if strings.TrimSpace(v.Buffer()) != deletefolder {
+		UpdateLog(g, fmt.Sprintf("Type %s exactly to confirm, or C-x to cancel", deletefolder))
+		return nil
+	}
+
+	folder, keys := deletefolder, deletekeys
+	closeDeleteFolder(g)
+	UpdateLog(g, fmt.Sprintf("Deleting %d secrets under %s", len(keys), folder))
+
+	ctx := jobctx
+	go func() {
+		deleted, err := api.DeleteSecrets(ctx, store, keys, func(done, total int) {
+			if done%10 != 0 && done != total {
+				return
+			}
+			g.Execute(func(g *gocui.Gui) error {
+				UpdateLog(g, fmt.Sprintf("Deleting %s: %d of %d deleted", folder, done, total))
+				return nil
+			})
+		})
+		g.Execute(func(g *gocui.Gui) error {
+			if err == context.Canceled {
+				UpdateLog(g, fmt.Sprintf("Deleting %s stopped after %d of %d secrets, the connection changed", folder, deleted, len(keys)))
+				return nil
+			}
+			if err != nil {
+				UpdateLog(g, fmt.Sprintf("ERROR: deleted %d of %d secrets under %s: %s", deleted, len(keys), folder, err))
+			} else {
+				UpdateLog(g, fmt.Sprintf("Deleted %d secrets under %s", deleted, folder))
+			}
+			loadTree(g, currentmount)
+			return nil
+		})
+	}()
+	return MainView(g, v)
+}
+
+func ScrollDeleteUp(g *gocui.Gui, v *gocui.View) error {
+	if lv, err := g.View("deletefolder"); err == nil {
+		ox, oy := lv.Origin()
+		if oy > 0 {
+			return lv.SetOrigin(ox, oy-1)
+		}
+	}
+	return nil
+}
+
+func ScrollDeleteDown(g *gocui.Gui, v *gocui.View) error {
+	if lv, err := g.View("deletefolder"); err == nil {
+		ox, oy := lv.Origin()
+		_, h := lv.Size()
+		if oy+h < len(deletekeys) {
+			return lv.SetOrigin(ox, oy+1)
+		}
+	}
+	return nil
+}
+
+func CancelDeleteFolder(g *gocui.Gui, v *gocui.View) error {
+	closeDeleteFolder(g)
+	return MainView(g, v)
+}
+
+func closeDeleteFolder(g *gocui.Gui) {
+	deletecancel()
+	deleting++
+	deletekeys = nil
+	deletelisted = false
+	deleteerr = nil
+	g.DeleteView("deleteconfirm")
+	g.DeleteView("deletefolder")
+}
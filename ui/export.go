@@ -0,0 +1,121 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jroimartin/gocui"
+	"github.com/rackerlabs/vault-commander/api"
+	"github.com/rackerlabs/vault-commander/transfer"
+)
+
+var exportpromptlegend = "Ret - next\nC-x - cancel"
+
+// exportprefix is the folder being exported once the export prompt has
+// moved on to asking where to. exporting numbers the exports so a
+// cancelled one does not report over the next.
+var exportprefix string
+var exportcancel context.CancelFunc = func() {}
+var exporting int
+
+func ExportPrompt(g *gocui.Gui, v *gocui.View) error {
+	if currentmount == "" {
+		return nil
+	}
+	exportprefix = ""
+	prefix := folderOf(mainLine(g))
+	if node := selectedNode(g); node != nil && node.folder {
+		prefix = node.path
+	}
+
+	length := len(prefix) + 10
+	if length < 50 {
+		length = 50
+	}
+	maxX, maxY := g.Size()
+	v = CreateView(g, "exportprompt", maxX/2-length/2, maxY/2, maxX/2+length/2, maxY/2+2)
+	v.Title = "Export Folder"
+	v.Clear()
+	fmt.Fprintln(v, prefix)
+	if err := v.SetCursor(len(prefix), 0); err != nil {
+		return err
+	}
+	UpdateLegend(g, exportpromptlegend)
+	return nil
+}
+
+// ExportNext takes the folder to export first, then the file or directory
+// to export it to.
+func ExportNext(g *gocui.Gui, v *gocui.View) error {
+	text := strings.TrimSpace(v.Buffer())
+	if text == "" {
+		return nil
+	}
+
+	if exportprefix == "" {
+		if !strings.HasSuffix(text, "/") {
+			text += "/"
+		}
+		exportprefix = text
+		v.Clear()
+		v.SetCursor(0, 0)
+		v.Title = "Export To (.json, .yaml or dir/)"
+		return nil
+	}
+
+	dest := api.ExpandHome(text)
+	if strings.HasSuffix(text, "/") && !strings.HasSuffix(dest, "/") {
+		dest += "/"
+	}
+	g.DeleteView("exportprompt")
+	runExport(g, exportprefix, dest)
+	return MainView(g, v)
+}
+
+func CancelExportPrompt(g *gocui.Gui, v *gocui.View) error {
+	g.DeleteView("exportprompt")
+	return MainView(g, v)
+}
+
+// runExport reads the secrets under prefix in the background and saves
+// them to dest, logging its progress.
+func runExport(g *gocui.Gui, prefix string, dest string) {
+	exportcancel()
+	ctx, cancel := context.WithCancel(context.Background())
+	exportcancel = cancel
+	exporting++
+	id := exporting
+	UpdateLog(g, fmt.Sprintf("Exporting secrets under %s to %s", prefix, dest))
+
+	go func() {
+		var last time.Time
+		secrets, err := transfer.Export(ctx, store, prefix, func(done, total int) {
+			if time.Since(last) < time.Second || done == total {
+				return
+			}
+			last = time.Now()
+			g.Execute(func(g *gocui.Gui) error {
+				if id == exporting {
+					UpdateLog(g, fmt.Sprintf("Exporting %s: %d of %d read", prefix, done, total))
+				}
+				return nil
+			})
+		})
+		if err == nil {
+			err = transfer.Save(dest, "", secrets)
+		}
+
+		g.Execute(func(g *gocui.Gui) error {
+			if err == context.Canceled {
+				UpdateLog(g, fmt.Sprintf("Export of %s cancelled", prefix))
+			} else if err != nil {
+				UpdateLog(g, fmt.Sprintf("ERROR: unable to export %s: %s", prefix, err))
+			} else {
+				UpdateLog(g, fmt.Sprintf("Exported %d secrets under %s to %s", len(secrets), prefix, dest))
+			}
+			return nil
+		})
+	}()
+}
@@ -0,0 +1,130 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jroimartin/gocui"
+)
+
+var filterlegend = "type to filter\n↑/↓ - select match\nRet - view secret\nC-x - clear filter"
+
+// filterkeys holds every secret on filtermount once it has been listed for
+// the filter, filtertext is the pattern typed so far. filtering is set
+// while the main view shows the filter results instead of the tree.
+var filterkeys []string
+var filtermount string
+var filtertext string
+var filtering bool
+
+// filterEditor edits the filter prompt and narrows the main view with
+// every change.
+type filterEditor struct {
+	g *gocui.Gui
+}
+
+var fe filterEditor
+
+func (e *filterEditor) Edit(v *gocui.View, key gocui.Key, ch rune, mod gocui.Modifier) {
+	switch {
+	case ch != 0 && mod == 0:
+		v.EditWrite(ch)
+	case key == gocui.KeySpace:
+		v.EditWrite(' ')
+	case key == gocui.KeyBackspace || key == gocui.KeyBackspace2:
+		v.EditDelete(true)
+	default:
+		return
+	}
+	filtertext = strings.TrimSpace(v.Buffer())
+	applyFilter(e.g)
+}
+
+func FilterPrompt(g *gocui.Gui, v *gocui.View) error {
+	if currentmount == "" {
+		return nil
+	}
+
+	fe.g = g
+	filtering = true
+	maxX, maxY := g.Size()
+	v = CreateView(g, "filter", 30, maxY-12, maxX-1, maxY-10)
+	v.Clear()
+	fmt.Fprint(v, filtertext)
+	v.SetCursor(len([]rune(filtertext)), 0)
+	if _, err := g.SetCurrentView("filter"); err != nil {
+		return err
+	}
+	UpdateLegend(g, filterlegend)
+
+	if filtermount == currentmount {
+		applyFilter(g)
+		return nil
+	}
+	mount := currentmount
+	filterkeys = nil
+	walkMount(g, mount, func(g *gocui.Gui, keys []string) {
+		filterkeys = keys
+		filtermount = mount
+		UpdateLog(g, fmt.Sprintf("Filtering %d secrets on %s mount", len(keys), mount))
+		applyFilter(g)
+	})
+	return nil
+}
+
+// applyFilter shows the secrets matching filtertext in the main view with
+// the cursor on the best match.
+func applyFilter(g *gocui.Gui) {
+	if !filtering || filterkeys == nil {
+		return
+	}
+
+	matches := fuzzyFilter(filtertext, filterkeys)
+	rows = make([]*treeNode, 0, len(matches))
+	for _, m := range matches {
+		rows = append(rows, &treeNode{path: m.key, name: m.key, label: highlight(m.key, m.positions)})
+	}
+	setMainTitle(g, fmt.Sprintf("Keys - %d of %d match %q", len(rows), len(filterkeys), filtertext))
+
+	best := ""
+	if len(rows) > 0 {
+		best = rows[0].path
+	}
+	drawRows(g, best)
+}
+
+func FilterCursorDown(g *gocui.Gui, v *gocui.View) error {
+	mv, _ := g.View("main")
+	return CursorDown(g, mv)
+}
+
+func FilterCursorUp(g *gocui.Gui, v *gocui.View) error {
+	mv, _ := g.View("main")
+	return CursorUp(g, mv)
+}
+
+// OpenFilterMatch closes the prompt, leaving the matches in the main view,
+// and shows the selected secret.
+func OpenFilterMatch(g *gocui.Gui, v *gocui.View) error {
+	g.DeleteView("filter")
+	mv, err := g.SetCurrentView("main")
+	if err != nil {
+		return err
+	}
+	UpdateLegend(g, mainLegend(g))
+	return ViewSecret(g, mv)
+}
+
+// ClearFilter closes the prompt and brings back the tree.
+func ClearFilter(g *gocui.Gui, v *gocui.View) error {
+	filtering = false
+	filtertext = ""
+	g.DeleteView("filter")
+	if _, err := g.SetCurrentView("main"); err != nil {
+		return err
+	}
+	setMainTitle(g, "Keys")
+	loadTree(g, currentmount)
+	UpdateLegend(g, mainLegend(g))
+	return nil
+}
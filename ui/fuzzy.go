@@ -0,0 +1,110 @@
+package ui
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// fuzzyMatch is a key matched by a fuzzy pattern, positions are the
+// indexes of the matched runes.
+type fuzzyMatch struct {
+	key       string
+	score     int
+	positions []int
+}
+
+// fuzzyFilter returns the keys containing the runes of pattern in order,
+// ignoring case, best matches first.
+func fuzzyFilter(pattern string, keys []string) []fuzzyMatch {
+	var matches []fuzzyMatch
+	if pattern == "" {
+		for _, key := range keys {
+			matches = append(matches, fuzzyMatch{key: key})
+		}
+		return matches
+	}
+	for _, key := range keys {
+		if score, positions, ok := fuzzyScore(pattern, key); ok {
+			matches = append(matches, fuzzyMatch{key: key, score: score, positions: positions})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score > matches[j].score
+		}
+		if len(matches[i].key) != len(matches[j].key) {
+			return len(matches[i].key) < len(matches[j].key)
+		}
+		return matches[i].key < matches[j].key
+	})
+	return matches
+}
+
+// fuzzyScore matches pattern against s. Runs of consecutive runes and
+// runes at the start of a path segment or word score higher, gaps lower.
+func fuzzyScore(pattern string, s string) (int, []int, bool) {
+	p := []rune(strings.ToLower(pattern))
+	r := []rune(strings.ToLower(s))
+	if len(p) == 0 {
+		return 0, nil, true
+	}
+
+	// Find where the first match ends, then walk back from there to the
+	// latest start so the matched runes are as close together as possible.
+	end, pi := -1, 0
+	for i := 0; i < len(r) && pi < len(p); i++ {
+		if r[i] == p[pi] {
+			pi++
+			end = i
+		}
+	}
+	if pi < len(p) {
+		return 0, nil, false
+	}
+	start := end
+	for i, pi := end, len(p)-1; i >= 0 && pi >= 0; i-- {
+		if r[i] == p[pi] {
+			pi--
+			start = i
+		}
+	}
+
+	positions := make([]int, 0, len(p))
+	for i, pi := start, 0; i <= end && pi < len(p); i++ {
+		if r[i] == p[pi] {
+			positions = append(positions, i)
+			pi++
+		}
+	}
+
+	score := 0
+	for i, pos := range positions {
+		score++
+		if i > 0 && pos == positions[i-1]+1 {
+			score += 5
+		}
+		if pos == 0 || !unicode.IsLetter(r[pos-1]) && !unicode.IsDigit(r[pos-1]) {
+			score += 3
+		}
+	}
+	score -= (end - start + 1) - len(p)
+	return score, positions, true
+}
+
+// highlight wraps the runes of s at positions in a colour.
+func highlight(s string, positions []int) string {
+	var b strings.Builder
+	next := 0
+	for i, ch := range []rune(s) {
+		if next < len(positions) && positions[next] == i {
+			b.WriteString("\x1b[33;1m")
+			b.WriteRune(ch)
+			b.WriteString("\x1b[0m")
+			next++
+			continue
+		}
+		b.WriteRune(ch)
+	}
+	return b.String()
+}
@@ -0,0 +1,40 @@
+package ui
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFuzzyFilter(t *testing.T) {
+	keys := []string{
+		"secret/app/prod/database",
+		"secret/db/backup",
+		"secret/dashboard/token",
+		"secret/app/staging/db",
+	}
+
+	var got []string
+	for _, m := range fuzzyFilter("db", keys) {
+		got = append(got, m.key)
+	}
+	expected := []string{"secret/db/backup", "secret/app/staging/db", "secret/dashboard/token", "secret/app/prod/database"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Test failed, expected: '%v', got:  '%v'", expected, got)
+	}
+
+	if m := fuzzyFilter("xyz", keys); len(m) != 0 {
+		t.Errorf("Test failed, expected no matches, got:  '%v'", m)
+	}
+}
+
+func TestFuzzyScorePositions(t *testing.T) {
+	_, positions, ok := fuzzyScore("Tok", "secret/dashboard/token")
+	if !ok || !reflect.DeepEqual(positions, []int{17, 18, 19}) {
+		t.Errorf("Test failed, expected: '%v', got:  '%v'", []int{17, 18, 19}, positions)
+	}
+
+	got := highlight("abc", []int{1})
+	if got != "a\x1b[33;1mb\x1b[0mc" {
+		t.Errorf("Test failed, expected highlighted b, got:  '%q'", got)
+	}
+}
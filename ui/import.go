@@ -0,0 +1,206 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jroimartin/gocui"
+	"github.com/rackerlabs/vault-commander/api"
+	"github.com/rackerlabs/vault-commander/transfer"
+)
+
+var importpromptlegend = "Ret - next\nC-x - cancel"
+var importlegend = "Ret - import\nr - merge/replace\nv - reveal values\n↑/↓ - move\nq - cancel"
+
+// importfile is the file being imported once the import prompt has moved
+// on to asking where to. importsecrets are the secrets read from it and
+// importplan what writing them would change, merging into existing
+// secrets unless importreplace is set. importing numbers the plans so only
+// the latest one fills the view.
+var importfile string
+var importsecrets transfer.Secrets
+var importplan []transfer.Item
+var importreplace bool
+var importreveal bool
+var importcancel context.CancelFunc = func() {}
+var importing int
+
+func ImportPrompt(g *gocui.Gui, v *gocui.View) error {
+	if currentmount == "" {
+		return nil
+	}
+	importfile = ""
+
+	maxX, maxY := g.Size()
+	v = CreateView(g, "importprompt", maxX/2-25, maxY/2, maxX/2+25, maxY/2+2)
+	v.Title = "Import File (.json, .yaml, .env or dir/)"
+	v.Clear()
+	if err := v.SetCursor(0, 0); err != nil {
+		return err
+	}
+	UpdateLegend(g, importpromptlegend)
+	return nil
+}
+
+// ImportNext takes the file to import first, then the path to import it
+// to, which may be left blank for documents and directories to keep the
+// paths they have.
+func ImportNext(g *gocui.Gui, v *gocui.View) error {
+	text := strings.TrimSpace(v.Buffer())
+
+	if importfile == "" {
+		if text == "" {
+			return nil
+		}
+		importfile = api.ExpandHome(text)
+		target := folderOf(mainLine(g))
+		v.Clear()
+		fmt.Fprintln(v, target)
+		v.SetCursor(len(target), 0)
+		v.Title = "Import To (blank to keep paths)"
+		return nil
+	}
+
+	secrets, err := transfer.Load(importfile, text)
+	if err != nil {
+		UpdateLog(g, fmt.Sprintf("ERROR: unable to import %s: %s", importfile, err))
+		return nil
+	}
+	g.DeleteView("importprompt")
+	importsecrets = secrets
+	importreplace = false
+	importreveal = false
+	planImport(g)
+	return nil
+}
+
+func CancelImportPrompt(g *gocui.Gui, v *gocui.View) error {
+	g.DeleteView("importprompt")
+	return MainView(g, v)
+}
+
+// planImport works out in the background what importing importsecrets
+// would change and shows the plan in the import view.
+func planImport(g *gocui.Gui) {
+	importcancel()
+	ctx, cancel := context.WithCancel(context.Background())
+	importcancel = cancel
+	importing++
+	id := importing
+	importplan = nil
+	replace := importreplace
+
+	maxX, maxY := g.Size()
+	v := CreateView(g, "import", -1, 0, maxX, maxY-9)
+	v.Highlight = true
+	v.SelBgColor = gocui.ColorGreen
+	v.SelFgColor = gocui.ColorBlack
+	v.Clear()
+	v.SetOrigin(0, 0)
+	v.SetCursor(0, 0)
+	v.Title = fmt.Sprintf("Reading %d secrets to import from %s", len(importsecrets), importfile)
+	g.SetCurrentView("import")
+	UpdateLegend(g, importlegend)
+
+	go func() {
+		plan, err := transfer.Plan(ctx, store, importsecrets, replace, nil)
+		g.Execute(func(g *gocui.Gui) error {
+			if id != importing {
+				return nil
+			}
+			if err != nil {
+				if err != context.Canceled {
+					UpdateLog(g, fmt.Sprintf("ERROR: unable to plan the import of %s: %s", importfile, err))
+				}
+				return nil
+			}
+			importplan = plan
+			if v, err := g.View("import"); err == nil {
+				renderImport(v)
+			}
+			return nil
+		})
+	}()
+}
+
+// renderImport lists every secret of the plan with the fields that change.
+func renderImport(v *gocui.View) {
+	mode := "merging into"
+	if importreplace {
+		mode = "replacing"
+	}
+	counts := transfer.Count(importplan)
+	v.Title = fmt.Sprintf("Import %s, %s existing secrets: %d created, %d changed, %d unchanged",
+		importfile, mode, counts[transfer.Create], counts[transfer.Change], counts[transfer.Unchanged])
+
+	v.Clear()
+	for _, item := range importplan {
+		fmt.Fprintf(v, "%-9s %s\n", item.Action, item.Path)
+		for _, c := range item.Changes {
+			fmt.Fprintf(v, "    %s\n", c.String(importreveal))
+		}
+	}
+}
+
+func ToggleImportMode(g *gocui.Gui, v *gocui.View) error {
+	importreplace = !importreplace
+	planImport(g)
+	return nil
+}
+
+func RevealImport(g *gocui.Gui, v *gocui.View) error {
+	importreveal = !importreveal
+	if importplan != nil {
+		renderImport(v)
+	}
+	return nil
+}
+
+// ApplyImport writes the plan in the background, logging its progress.
+func ApplyImport(g *gocui.Gui, v *gocui.View) error {
+	if importplan == nil {
+		return nil
+	}
+	plan, file := importplan, importfile
+	closeImport(g)
+	UpdateLog(g, fmt.Sprintf("Importing %s", file))
+
+	ctx := jobctx
+	go func() {
+		err := transfer.Apply(ctx, store, plan, func(done, total int) {
+			g.Execute(func(g *gocui.Gui) error {
+				UpdateLog(g, fmt.Sprintf("Importing %s: %d of %d written", file, done, total))
+				return nil
+			})
+		})
+		g.Execute(func(g *gocui.Gui) error {
+			if err == context.Canceled {
+				UpdateLog(g, fmt.Sprintf("Import of %s stopped, the connection changed", file))
+				return nil
+			}
+			if err != nil {
+				UpdateLog(g, fmt.Sprintf("ERROR: import of %s stopped: %s", file, err))
+			} else {
+				counts := transfer.Count(plan)
+				UpdateLog(g, fmt.Sprintf("Imported %s: %d created, %d changed", file, counts[transfer.Create], counts[transfer.Change]))
+			}
+			loadTree(g, currentmount)
+			return nil
+		})
+	}()
+	return MainView(g, v)
+}
+
+func CancelImport(g *gocui.Gui, v *gocui.View) error {
+	closeImport(g)
+	return MainView(g, v)
+}
+
+func closeImport(g *gocui.Gui) {
+	importcancel()
+	importing++
+	importplan = nil
+	importsecrets = nil
+	g.DeleteView("import")
+}
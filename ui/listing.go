@@ -0,0 +1,124 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jroimartin/gocui"
+	"github.com/rackerlabs/vault-commander/api"
+)
+
+// listcancel stops the key listing running in the background. listing
+// numbers the listings so only the latest one fills the main view.
+var listcancel context.CancelFunc = func() {}
+var listing int
+
+// jobctx is the context of the deletes, copies and imports running in the
+// background. It is cancelled when the connection changes so they don't
+// carry on against the new one.
+var jobctx, jobcancel = context.WithCancel(context.Background())
+
+// loadKeys lists every secret under mount in the background and fills the
+// main view once the listing is complete. It backs the flat list mode of
+// the main view.
+func loadKeys(g *gocui.Gui, mount string) {
+	walkMount(g, mount, func(g *gocui.Gui, keys []string) {
+		showKeys(g, keys)
+		UpdateLog(g, fmt.Sprintf("Viewing %d secrets on %s mount", len(keys), mount))
+	})
+}
+
+// walkMount lists every secret under mount in the background, showing the
+// progress in the title of the main view, and calls done with them once
+// the listing is complete.
+func walkMount(g *gocui.Gui, mount string, done func(*gocui.Gui, []string)) {
+	listcancel()
+	if mount == "" {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	listcancel = cancel
+	listing++
+	id := listing
+
+	setMainTitle(g, fmt.Sprintf("Keys - listing %s", mount))
+	go func() {
+		var last time.Time
+		keys, err := api.ListAllKeys(ctx, store, mount, func(n int) {
+			if time.Since(last) < 100*time.Millisecond {
+				return
+			}
+			last = time.Now()
+			g.Execute(func(g *gocui.Gui) error {
+				if id == listing {
+					setMainTitle(g, fmt.Sprintf("Keys - listing %s: %d found", mount, n))
+				}
+				return nil
+			})
+		})
+
+		g.Execute(func(g *gocui.Gui) error {
+			if id != listing {
+				return nil
+			}
+			setMainTitle(g, "Keys")
+			if err == context.Canceled {
+				UpdateLog(g, fmt.Sprintf("Listing of %s cancelled", mount))
+				return nil
+			} else if err != nil {
+				UpdateLog(g, fmt.Sprintf("ERROR: unable to list %s: %s", mount, err))
+				return nil
+			}
+			done(g, keys)
+			return nil
+		})
+	}()
+}
+
+// resetListing forgets the keys listed from the previous connection and
+// stops everything still reading from it, for when the profile or
+// namespace changes under the main view.
+func resetListing() {
+	listcancel()
+	listing++
+	tree = nil
+	rows = nil
+	filtering = false
+	filtermount = ""
+	filterkeys = nil
+
+	searchcancel()
+	searching++
+	searchresults = nil
+	exportcancel()
+	exporting++
+
+	jobcancel()
+	jobctx, jobcancel = context.WithCancel(context.Background())
+}
+
+// CancelListing stops the listing of the mount, and any export running.
+func CancelListing(g *gocui.Gui, v *gocui.View) error {
+	listcancel()
+	exportcancel()
+	return nil
+}
+
+func showKeys(g *gocui.Gui, keys []string) {
+	selected := mainLine(g)
+	rows = make([]*treeNode, 0, len(keys))
+	for _, key := range keys {
+		rows = append(rows, &treeNode{path: key, name: key})
+	}
+	drawRows(g, selected)
+	if cv := g.CurrentView(); cv != nil && cv.Name() == "main" {
+		UpdateLegend(g, mainLegend(g))
+	}
+}
+
+func setMainTitle(g *gocui.Gui, title string) {
+	if v, err := g.View("main"); err == nil {
+		v.Title = title
+	}
+}
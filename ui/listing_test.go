@@ -0,0 +1,57 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/rackerlabs/vault-commander/api"
+	"github.com/rackerlabs/vault-commander/transfer"
+)
+
+// A switch of profile or namespace partway through a background job stops
+// it before it writes to the new connection.
+func TestResetListingStopsJobs(t *testing.T) {
+	s := useMemoryStore(t)
+	for i := 0; i < 10; i++ {
+		s.Write(fmt.Sprintf("secret/app/%02d", i), nil, map[string]interface{}{"n": i})
+	}
+
+	pairs, _, err := api.CopyPairs(context.Background(), store, "secret/app/", "secret/copy/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = api.CopySecrets(jobctx, store, pairs, true, func(done, total int) {
+		if done == 3 {
+			resetListing()
+		}
+	})
+	copied, _ := api.ListAllKeys(context.Background(), s, "secret/copy/", nil)
+	left, _ := api.ListAllKeys(context.Background(), s, "secret/app/", nil)
+	if err != context.Canceled || len(copied) != 3 || len(left) != 10 {
+		t.Errorf("Test failed, expected: '3' copied and none moved, got:  '%d' copied and %d left, %v", len(copied), len(left), err)
+	}
+
+	plan, _ := transfer.Plan(context.Background(), store, transfer.Secrets{"secret/a": {"n": 1}, "secret/b": {"n": 2}}, false, nil)
+	err = transfer.Apply(jobctx, store, plan, func(done, total int) {
+		resetListing()
+	})
+	if b, _ := s.Read(context.Background(), "secret/b"); err != context.Canceled || b.Data != nil {
+		t.Errorf("Test failed, expected the import stopped after the first write, got:  '%v'", err)
+	}
+
+	ctx := jobctx
+	var deleted int
+	_, err = api.DeleteSecrets(ctx, store, left, func(done, total int) {
+		deleted = done
+		if done == 1 {
+			resetListing()
+		}
+	})
+	if err != context.Canceled || deleted == len(left) {
+		t.Errorf("Test failed, expected the delete stopped, got:  '%d' deleted, %v", deleted, err)
+	}
+	if jobctx.Err() != nil {
+		t.Error("Test failed, expected the jobs of the new connection to run")
+	}
+}
@@ -0,0 +1,206 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jroimartin/gocui"
+	"github.com/rackerlabs/vault-commander/api"
+)
+
+var loginlegend = "↑/↓ - select method\nRet - choose method\nC-c - quit"
+var loginfieldlegend = "Ret - next field\nC-x - back to methods"
+var savetokenlegend = "y - save token\nn - don't save"
+
+// loginmethod and loginparams hold the login being entered, loginfield is
+// the index of the field currently prompted for.
+var loginmethod string
+var loginparams map[string]string
+var loginfield int
+var logintoken string
+
+// pendingmount is the mount given on the command line, opened once the
+// user has logged in. returnview and returnlegend are where the user was
+// when they were sent to log in again.
+var pendingmount string
+var returnview string
+var returnlegend string
+
+// maskEditor keeps what is typed in value and only shows asterisks.
+type maskEditor struct {
+	value []rune
+}
+
+var me maskEditor
+
+func (e *maskEditor) Edit(v *gocui.View, key gocui.Key, ch rune, mod gocui.Modifier) {
+	switch {
+	case ch != 0 && mod == 0:
+		e.value = append(e.value, ch)
+		v.EditWrite('*')
+	case key == gocui.KeySpace:
+		e.value = append(e.value, ' ')
+		v.EditWrite('*')
+	case key == gocui.KeyBackspace || key == gocui.KeyBackspace2:
+		if len(e.value) > 0 {
+			e.value = e.value[:len(e.value)-1]
+			v.EditDelete(true)
+		}
+	}
+}
+
+// needsLogin reports whether err means the user has to log in again.
+func needsLogin(err error) bool {
+	return err == api.ErrNoToken || api.IsForbidden(err)
+}
+
+// loggingIn reports whether the login screen is open.
+func loggingIn(g *gocui.Gui) bool {
+	for _, name := range []string{"login", "loginfield", "savetokenprompt"} {
+		if _, err := g.View(name); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// LoginScreen shows the list of auth methods to log in with.
+func LoginScreen(g *gocui.Gui, reason error) error {
+	if cv := g.CurrentView(); cv != nil && !loggingIn(g) {
+		returnview = cv.Name()
+		returnlegend = legendtext
+	}
+
+	maxX, maxY := g.Size()
+	v := CreateView(g, "login", maxX/2-20, maxY/2-4, maxX/2+20, maxY/2+3)
+	v.Highlight = true
+	v.SelBgColor = gocui.ColorGreen
+	v.SelFgColor = gocui.ColorBlack
+	v.Clear()
+	for i, method := range api.AuthMethods {
+		fmt.Fprintln(v, method)
+		if method == api.DefaultAuthMethod() {
+			v.SetCursor(0, i)
+		}
+	}
+	if _, err := g.SetCurrentView("login"); err != nil {
+		return err
+	}
+
+	UpdateLegend(g, loginlegend)
+	if reason != nil {
+		UpdateLog(g, fmt.Sprintf("Login required: %s", reason))
+	}
+	return nil
+}
+
+func ChooseLoginMethod(g *gocui.Gui, v *gocui.View) error {
+	_, cy := v.Cursor()
+	method, err := v.Line(cy)
+	if err != nil || method == "" {
+		return nil
+	}
+
+	loginmethod = method
+	loginparams = map[string]string{}
+	loginfield = 0
+	return promptLoginField(g)
+}
+
+func promptLoginField(g *gocui.Gui) error {
+	field := api.LoginFields(loginmethod)[loginfield]
+
+	g.DeleteView("loginfield")
+	maxX, maxY := g.Size()
+	v := CreateView(g, "loginfield", maxX/2-20, maxY/2, maxX/2+20, maxY/2+2)
+	v.Title = fmt.Sprintf("%s %s", loginmethod, field.Name)
+	if field.Secret {
+		me = maskEditor{}
+		v.Editor = &me
+	} else {
+		fmt.Fprint(v, field.Default)
+		if err := v.SetCursor(len(field.Default), 0); err != nil {
+			return err
+		}
+	}
+
+	UpdateLegend(g, loginfieldlegend)
+	return nil
+}
+
+func NextLoginField(g *gocui.Gui, v *gocui.View) error {
+	fields := api.LoginFields(loginmethod)
+	field := fields[loginfield]
+	if field.Secret {
+		loginparams[field.Name] = string(me.value)
+	} else {
+		loginparams[field.Name] = strings.TrimSpace(v.Buffer())
+	}
+
+	loginfield++
+	if loginfield < len(fields) {
+		return promptLoginField(g)
+	}
+
+	g.DeleteView("loginfield")
+	token, err := api.Login(loginmethod, loginparams)
+	loginparams = nil
+	if err != nil {
+		UpdateLog(g, fmt.Sprintf("ERROR: %s login failed: %s", loginmethod, err))
+		return LoginScreen(g, nil)
+	}
+
+	logintoken = token
+	UpdateLog(g, fmt.Sprintf("Logged in with %s", loginmethod))
+	maxX, maxY := g.Size()
+	v = CreateView(g, "savetokenprompt", maxX/2-20, maxY/2, maxX/2+20, maxY/2+2)
+	fmt.Fprintln(v, "Save token to the token file? (y/n)")
+	UpdateLegend(g, savetokenlegend)
+	return nil
+}
+
+func CancelLoginField(g *gocui.Gui, v *gocui.View) error {
+	g.DeleteView("loginfield")
+	loginparams = nil
+	return LoginScreen(g, nil)
+}
+
+func SaveLoginToken(g *gocui.Gui, v *gocui.View) error {
+	if err := api.SaveToken(logintoken); err != nil {
+		UpdateLog(g, fmt.Sprintf("ERROR: unable to save token: %s", err))
+	} else {
+		UpdateLog(g, "Saved token to the token file")
+	}
+	return finishLogin(g)
+}
+
+func SkipLoginToken(g *gocui.Gui, v *gocui.View) error {
+	return finishLogin(g)
+}
+
+// finishLogin closes the login views and reloads everything that was
+// loaded with the old token.
+func finishLogin(g *gocui.Gui) error {
+	logintoken = ""
+	g.DeleteView("savetokenprompt")
+	g.DeleteView("login")
+
+	reloadMounts(g)
+
+	if pendingmount != "" {
+		mp := pendingmount
+		pendingmount = ""
+		return initMount(g, mp)
+	}
+	if returnview != "" {
+		view, legend := returnview, returnlegend
+		returnview, returnlegend = "", ""
+		if _, err := g.SetCurrentView(view); err == nil {
+			UpdateLegend(g, legend)
+			return nil
+		}
+	}
+	g.SetCurrentView("side")
+	UpdateLegend(g, sidelegend)
+	return nil
+}
@@ -0,0 +1,94 @@
+package ui
+
+import (
+	"sort"
+
+	"github.com/rackerlabs/vault-commander/diff"
+)
+
+// fieldConflict is a field that was changed both in the local edit and on
+// the server since the secret was opened, to different values.
+type fieldConflict struct {
+	field     string
+	mine      interface{}
+	mineSet   bool
+	theirs    interface{}
+	theirsSet bool
+	useMine   bool
+}
+
+// threeWayMerge merges the local edit and the current server value of a
+// secret against the value both started from. Fields changed on only one
+// side are merged automatically, the rest are returned as conflicts.
+func threeWayMerge(base, mine, theirs map[string]interface{}) (map[string]interface{}, []fieldConflict) {
+	fields := map[string]bool{}
+	for _, m := range []map[string]interface{}{base, mine, theirs} {
+		for k := range m {
+			fields[k] = true
+		}
+	}
+
+	merged := map[string]interface{}{}
+	var conflicts []fieldConflict
+	for field := range fields {
+		b, bok := base[field]
+		m, mok := mine[field]
+		t, tok := theirs[field]
+
+		switch {
+		case sameValue(m, mok, t, tok) || sameValue(b, bok, t, tok):
+			if mok {
+				merged[field] = m
+			}
+		case sameValue(b, bok, m, mok):
+			if tok {
+				merged[field] = t
+			}
+		default:
+			conflicts = append(conflicts, fieldConflict{
+				field:     field,
+				mine:      m,
+				mineSet:   mok,
+				theirs:    t,
+				theirsSet: tok,
+				useMine:   true,
+			})
+		}
+	}
+
+	sort.Slice(conflicts, func(i, j int) bool {
+		return conflicts[i].field < conflicts[j].field
+	})
+	return merged, conflicts
+}
+
+// resolveConflicts applies the side picked for each conflicting field to
+// the automatically merged fields.
+func resolveConflicts(merged map[string]interface{}, conflicts []fieldConflict) map[string]interface{} {
+	resolved := make(map[string]interface{}, len(merged)+len(conflicts))
+	for k, v := range merged {
+		resolved[k] = v
+	}
+	for _, c := range conflicts {
+		value, set := c.theirs, c.theirsSet
+		if c.useMine {
+			value, set = c.mine, c.mineSet
+		}
+		if set {
+			resolved[c.field] = value
+		}
+	}
+	return resolved
+}
+
+// sameValue compares two field values with diff.Equal, either of which may
+// be unset.
+func sameValue(a interface{}, aok bool, b interface{}, bok bool) bool {
+	if aok != bok {
+		return false
+	}
+	if !aok {
+		return true
+	}
+	return diff.Equal(a, b)
+}
@@ -0,0 +1,45 @@
+package ui
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestThreeWayMerge(t *testing.T) {
+	base := map[string]interface{}{"user": "app", "pass": "old", "port": json.Number("5432"), "gone": "x"}
+	mine := map[string]interface{}{"user": "app", "pass": "mine", "port": float64(5432), "host": "db1"}
+	theirs := map[string]interface{}{"user": "svc", "pass": "theirs", "port": json.Number("5432"), "gone": "x"}
+
+	merged, conflicts := threeWayMerge(base, mine, theirs)
+
+	expected := map[string]interface{}{"user": "svc", "port": float64(5432), "host": "db1"}
+	if !reflect.DeepEqual(merged, expected) {
+		t.Errorf("Test failed, expected: '%v', got:  '%v'", expected, merged)
+	}
+	if len(conflicts) != 1 || conflicts[0].field != "pass" {
+		t.Fatalf("Test failed, expected a single conflict on pass, got: '%v'", conflicts)
+	}
+
+	conflicts[0].useMine = false
+	resolved := resolveConflicts(merged, conflicts)
+	if resolved["pass"] != "theirs" {
+		t.Errorf("Test failed, expected: 'theirs', got:  '%v'", resolved["pass"])
+	}
+}
+
+func TestThreeWayMergeRemovedField(t *testing.T) {
+	base := map[string]interface{}{"key": "a"}
+	mine := map[string]interface{}{}
+	theirs := map[string]interface{}{"key": "b"}
+
+	merged, conflicts := threeWayMerge(base, mine, theirs)
+	if len(merged) != 0 || len(conflicts) != 1 {
+		t.Fatalf("Test failed, expected one conflict, got: '%v' '%v'", merged, conflicts)
+	}
+
+	resolved := resolveConflicts(merged, conflicts)
+	if _, ok := resolved["key"]; ok {
+		t.Errorf("Test failed, expected key to stay removed, got:  '%v'", resolved)
+	}
+}
@@ -0,0 +1,95 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jroimartin/gocui"
+	"github.com/rackerlabs/vault-commander/api"
+)
+
+var namespaceslegend = "↑/↓ - select namespace\nRet - enter namespace\nq - back"
+
+func NamespaceBrowser(g *gocui.Gui, v *gocui.View) error {
+	children, err := api.ListNamespaces()
+	if err != nil {
+		UpdateLog(g, fmt.Sprintf("ERROR: unable to list namespaces: %s", err))
+		return nil
+	}
+
+	current := strings.Trim(api.Namespace(), "/")
+	_, maxY := g.Size()
+	v = CreateView(g, "namespaces", 1, 1, 30, maxY-10)
+	v.Title = "Namespaces in " + displayNamespace(current)
+	v.Highlight = true
+	v.SelBgColor = gocui.ColorGreen
+	v.SelFgColor = gocui.ColorBlack
+	v.Clear()
+	if current != "" {
+		fmt.Fprintln(v, "..")
+	}
+	for _, ns := range children {
+		fmt.Fprintln(v, ns)
+	}
+	v.SetCursor(0, 0)
+	v.SetOrigin(0, 0)
+
+	if _, err := g.SetCurrentView("namespaces"); err != nil {
+		return err
+	}
+	UpdateLegend(g, namespaceslegend)
+	return nil
+}
+
+func EnterNamespace(g *gocui.Gui, v *gocui.View) error {
+	_, cy := v.Cursor()
+	line, err := v.Line(cy)
+	if err != nil || line == "" {
+		return nil
+	}
+
+	ns := strings.Trim(api.Namespace(), "/")
+	if line == ".." {
+		if i := strings.LastIndex(ns, "/"); i >= 0 {
+			ns = ns[:i]
+		} else {
+			ns = ""
+		}
+	} else if ns == "" {
+		ns = strings.Trim(line, "/")
+	} else {
+		ns = ns + "/" + strings.Trim(line, "/")
+	}
+
+	switchNamespace(g, ns)
+	return NamespaceBrowser(g, v)
+}
+
+func CloseNamespaces(g *gocui.Gui, v *gocui.View) error {
+	g.DeleteView("namespaces")
+	g.SetCurrentView("side")
+	UpdateLegend(g, sidelegend)
+	return nil
+}
+
+// switchNamespace points the client at namespace ns and reloads the mounts
+// without restarting.
+func switchNamespace(g *gocui.Gui, ns string) {
+	// Stop the background walks before the shared client changes under them.
+	resetListing()
+	g.DeleteView("search")
+	api.SetNamespace(ns)
+
+	x, _ := g.View("main")
+	x.Clear()
+	currentmount = ""
+	reloadMounts(g)
+	UpdateLog(g, fmt.Sprintf("Switched to namespace %s", displayNamespace(ns)))
+}
+
+func displayNamespace(ns string) string {
+	if ns == "" {
+		return "root"
+	}
+	return ns
+}
@@ -0,0 +1,96 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jroimartin/gocui"
+	"github.com/rackerlabs/vault-commander/api"
+)
+
+var profileslegend = "↑/↓ - select profile\nRet - switch profile\nq - back"
+
+func ProfilePicker(g *gocui.Gui, v *gocui.View) error {
+	profiles, err := api.LoadProfiles()
+	if err != nil {
+		UpdateLog(g, err.Error())
+		return nil
+	}
+	if len(profiles) == 0 {
+		path, _ := api.ProfilesPath()
+		UpdateLog(g, fmt.Sprintf("No profiles found in %s", path))
+		return nil
+	}
+
+	maxX, maxY := g.Size()
+	v = CreateView(g, "profiles", maxX/2-25, maxY/2-6, maxX/2+25, maxY/2+6)
+	v.Highlight = true
+	v.SelBgColor = gocui.ColorGreen
+	v.SelFgColor = gocui.ColorBlack
+	v.Clear()
+	for _, p := range profiles {
+		marker := " "
+		if current := api.CurrentProfile(); current != nil && current.Name == p.Name {
+			marker = "*"
+		}
+		fmt.Fprintf(v, "%s %-16s %s\n", marker, p.Name, p.Address)
+	}
+
+	UpdateLegend(g, profileslegend)
+	return nil
+}
+
+func SwitchProfile(g *gocui.Gui, v *gocui.View) error {
+	_, cy := v.Cursor()
+	line, err := v.Line(cy)
+	fields := strings.Fields(strings.TrimPrefix(line, "*"))
+	if err != nil || len(fields) == 0 {
+		return nil
+	}
+
+	p, err := api.FindProfile(fields[0])
+	if err == nil {
+		err = api.UseProfile(p)
+	}
+	if err != nil {
+		UpdateLog(g, fmt.Sprintf("ERROR: unable to switch profile: %s", err))
+		return nil
+	}
+	g.DeleteView("profiles")
+
+	resetListing()
+	g.DeleteView("search")
+	x, _ := g.View("main")
+	x.Clear()
+	currentmount = ""
+	reloadMounts(g)
+	UpdateLog(g, fmt.Sprintf("Switched to profile %s (%s)", p.Name, api.Address()))
+
+	if err := api.CheckToken(); needsLogin(err) {
+		return LoginScreen(g, err)
+	}
+	g.SetCurrentView("side")
+	UpdateLegend(g, sidelegend)
+	return nil
+}
+
+func CloseProfiles(g *gocui.Gui, v *gocui.View) error {
+	g.DeleteView("profiles")
+	g.SetCurrentView("side")
+	UpdateLegend(g, sidelegend)
+	return nil
+}
+
+// reloadMounts redraws everything that depends on the client or token:
+// the mounts list, the status bar and the capability cache.
+func reloadMounts(g *gocui.Gui) {
+	v, _ := g.View("side")
+	v.Clear()
+	v.SetCursor(0, 0)
+	v.SetOrigin(0, 0)
+	for _, mount := range store.Mounts() {
+		fmt.Fprintln(v, mount)
+	}
+	refreshStatus(g)
+	resetCapabilities()
+}
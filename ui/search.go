@@ -0,0 +1,190 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jroimartin/gocui"
+	"github.com/rackerlabs/vault-commander/api"
+)
+
+var searchpromptlegend = "Ret - next\nC-x - cancel"
+var searchlegend = "↑/↓ - select result\nRet - view secret\nC-x - stop search\nq - back"
+
+// searchprefix is the folder being searched, searchresults the matches
+// shown in the search view. searching numbers the searches so only the
+// latest one fills the view.
+var searchprefix string
+var searchresults []api.SearchMatch
+var searchcancel context.CancelFunc = func() {}
+var searching int
+
+func SearchPrompt(g *gocui.Gui, v *gocui.View) error {
+	if currentmount == "" {
+		return nil
+	}
+	searchprefix = ""
+	prefix := folderOf(mainLine(g))
+
+	length := len(prefix) + 10
+	if length < 50 {
+		length = 50
+	}
+	maxX, maxY := g.Size()
+	v = CreateView(g, "searchprompt", maxX/2-length/2, maxY/2, maxX/2+length/2, maxY/2+2)
+	v.Title = "Search Under"
+	v.Clear()
+	fmt.Fprintln(v, prefix)
+	if err := v.SetCursor(len(prefix), 0); err != nil {
+		return err
+	}
+	UpdateLegend(g, searchpromptlegend)
+	return nil
+}
+
+// SearchNext takes the folder to search first, then what to search for.
+func SearchNext(g *gocui.Gui, v *gocui.View) error {
+	text := strings.TrimSpace(v.Buffer())
+	if text == "" {
+		return nil
+	}
+
+	if searchprefix == "" {
+		if !strings.HasSuffix(text, "/") {
+			text += "/"
+		}
+		searchprefix = text
+		v.Clear()
+		v.SetCursor(0, 0)
+		v.Title = "Search For (text or /regex/)"
+		return nil
+	}
+
+	re, err := api.SearchPattern(text)
+	if err != nil {
+		UpdateLog(g, fmt.Sprintf("ERROR: invalid regular expression: %s", err))
+		return nil
+	}
+	g.DeleteView("searchprompt")
+	runSearch(g, searchprefix, text, re)
+	return nil
+}
+
+func CancelSearchPrompt(g *gocui.Gui, v *gocui.View) error {
+	g.DeleteView("searchprompt")
+	return MainView(g, v)
+}
+
+// runSearch reads the secrets under prefix in the background and lists
+// the matching fields in the search view.
+func runSearch(g *gocui.Gui, prefix string, text string, re *regexp.Regexp) {
+	searchcancel()
+	ctx, cancel := context.WithCancel(context.Background())
+	searchcancel = cancel
+	searching++
+	id := searching
+	searchresults = nil
+
+	maxX, maxY := g.Size()
+	v := CreateView(g, "search", -1, 0, maxX, maxY-9)
+	v.Highlight = true
+	v.SelBgColor = gocui.ColorGreen
+	v.SelFgColor = gocui.ColorBlack
+	v.Clear()
+	v.Title = fmt.Sprintf("Searching %s for %s", prefix, text)
+	UpdateLegend(g, searchlegend)
+	UpdateLog(g, fmt.Sprintf("Searching secrets under %s for %s", prefix, text))
+
+	go func() {
+		var last time.Time
+		matches, err := api.SearchSecrets(ctx, store, prefix, re, func(done, total int) {
+			if time.Since(last) < 100*time.Millisecond && done < total {
+				return
+			}
+			last = time.Now()
+			g.Execute(func(g *gocui.Gui) error {
+				if v, err := g.View("search"); err == nil && id == searching {
+					v.Title = fmt.Sprintf("Searching %s for %s: %d of %d read", prefix, text, done, total)
+				}
+				return nil
+			})
+		})
+
+		g.Execute(func(g *gocui.Gui) error {
+			if id != searching {
+				return nil
+			}
+			v, verr := g.View("search")
+			if err == context.Canceled {
+				UpdateLog(g, fmt.Sprintf("Search of %s cancelled", prefix))
+				if verr == nil {
+					v.Title = fmt.Sprintf("Search of %s for %s cancelled", prefix, text)
+				}
+				return nil
+			} else if err != nil {
+				UpdateLog(g, fmt.Sprintf("ERROR: unable to search %s: %s", prefix, err))
+				return nil
+			}
+
+			UpdateLog(g, fmt.Sprintf("Found %d matches for %s under %s", len(matches), text, prefix))
+			if verr != nil {
+				return nil
+			}
+			searchresults = matches
+			v.Title = fmt.Sprintf("%d matches for %s under %s", len(matches), text, prefix)
+			renderSearch(v)
+			return nil
+		})
+	}()
+}
+
+// renderSearch lists the matches with their values masked.
+func renderSearch(v *gocui.View) {
+	v.Clear()
+	for _, m := range searchresults {
+		if m.InValue {
+			fmt.Fprintf(v, "%s  %s = ********\n", m.Path, m.Field)
+		} else {
+			fmt.Fprintf(v, "%s  %s (field name)\n", m.Path, m.Field)
+		}
+	}
+	v.SetCursor(0, 0)
+	v.SetOrigin(0, 0)
+}
+
+func OpenSearchResult(g *gocui.Gui, v *gocui.View) error {
+	_, oy := v.Origin()
+	_, cy := v.Cursor()
+	if i := oy + cy; i < len(searchresults) {
+		return showSecret(g, searchresults[i].Path)
+	}
+	return nil
+}
+
+func StopSearch(g *gocui.Gui, v *gocui.View) error {
+	searchcancel()
+	return nil
+}
+
+func CloseSearch(g *gocui.Gui, v *gocui.View) error {
+	searchcancel()
+	searchresults = nil
+	return MainView(g, v)
+}
+
+// CloseSecret leaves the secret view, back to the search results when the
+// secret was opened from them.
+func CloseSecret(g *gocui.Gui, v *gocui.View) error {
+	if _, err := g.View("search"); err != nil {
+		return MainView(g, v)
+	}
+	g.DeleteView("secret")
+	if _, err := g.SetCurrentView("search"); err != nil {
+		return err
+	}
+	UpdateLegend(g, searchlegend)
+	return nil
+}
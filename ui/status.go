@@ -0,0 +1,78 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jroimartin/gocui"
+	"github.com/rackerlabs/vault-commander/api"
+)
+
+// statusinterval is how often the status bar is redrawn so the TTL counts
+// down between token lookups.
+var statusinterval = 5 * time.Second
+
+// tokeninfo is the result of the last token lookup and tokenlookup when it
+// was made. currentmount is the mount shown in the main view. They are only
+// touched from the gocui main loop.
+var tokeninfo *api.TokenInfo
+var tokenlookup time.Time
+var currentmount string
+
+// setTokenInfo records a token lookup made outside the main loop and
+// redraws the status bar.
+func setTokenInfo(g *gocui.Gui, info *api.TokenInfo) {
+	g.Execute(func(g *gocui.Gui) error {
+		tokeninfo = info
+		tokenlookup = time.Now()
+		UpdateStatus(g)
+		return nil
+	})
+}
+
+// refreshStatus looks up the token and redraws the status bar.
+func refreshStatus(g *gocui.Gui) {
+	info, err := api.LookupToken()
+	if err != nil {
+		info = nil
+	}
+	tokeninfo = info
+	tokenlookup = time.Now()
+	UpdateStatus(g)
+}
+
+func UpdateStatus(g *gocui.Gui) {
+	v, err := g.View("status")
+	if err != nil {
+		return
+	}
+
+	name, policies, ttl := "-", "-", "-"
+	if tokeninfo != nil {
+		name = tokeninfo.DisplayName
+		policies = strings.Join(tokeninfo.Policies, ",")
+		if tokeninfo.TTL == 0 {
+			ttl = "never expires"
+		} else {
+			remaining := tokeninfo.TTL - time.Since(tokenlookup)
+			if remaining < 0 {
+				remaining = 0
+			}
+			ttl = remaining.Round(time.Second).String()
+		}
+	}
+
+	mount := currentmount
+	if mount == "" {
+		mount = "-"
+	}
+	namespace := displayNamespace(strings.Trim(api.Namespace(), "/"))
+
+	v.Clear()
+	if p := api.CurrentProfile(); p != nil {
+		fmt.Fprintf(v, " [%s]", p.Name)
+	}
+	fmt.Fprintf(v, " %s | token: %s | policies: %s | ttl: %s | mount: %s | namespace: %s",
+		api.Address(), name, policies, ttl, mount, namespace)
+}
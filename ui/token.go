@@ -0,0 +1,108 @@
+package ui
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jroimartin/gocui"
+	"github.com/rackerlabs/vault-commander/api"
+)
+
+// tokencheckinterval is how often the token is looked up, tokens with less
+// than renewthreshold left are renewed and warned about.
+var tokencheckinterval = 30 * time.Second
+var renewthreshold = 10 * time.Minute
+
+// expirywarnings are the remaining TTLs at which a warning is logged.
+var expirywarnings = []time.Duration{10 * time.Minute, 5 * time.Minute, time.Minute}
+
+// WatchToken starts a goroutine that renews the token before it expires,
+// warns in the log view as expiry gets close and opens the login screen
+// once the token is no longer usable. Everything touching the views goes
+// through g.Execute.
+func WatchToken(g *gocui.Gui) {
+	go func() {
+		warned := checkToken(g, -1)
+		tokenticker := time.NewTicker(tokencheckinterval)
+		statusticker := time.NewTicker(statusinterval)
+		for {
+			select {
+			case <-tokenticker.C:
+				warned = checkToken(g, warned)
+			case <-statusticker.C:
+				g.Execute(func(g *gocui.Gui) error {
+					UpdateStatus(g)
+					warnStale(g)
+					return nil
+				})
+			}
+		}
+	}()
+}
+
+// checkToken runs one round of the token watcher. warned is the smallest
+// expiry warning already logged, or -1 if none was.
+func checkToken(g *gocui.Gui, warned time.Duration) time.Duration {
+	info, err := api.LookupToken()
+	if err != nil {
+		if needsLogin(err) {
+			reauthenticate(g, fmt.Errorf("token expired or was revoked"))
+		} else {
+			logAsync(g, fmt.Sprintf("ERROR: unable to look up token: %s", err))
+		}
+		return warned
+	}
+	defer setTokenInfo(g, info)
+
+	// Tokens without a TTL, like root tokens, never expire.
+	if info.TTL == 0 {
+		return -1
+	}
+
+	ttl := info.TTL
+	if info.Renewable && ttl < renewthreshold {
+		renewed, err := api.RenewToken()
+		if err != nil {
+			logAsync(g, fmt.Sprintf("ERROR: unable to renew token: %s", err))
+		} else if ttl = renewed; ttl >= renewthreshold {
+			logAsync(g, fmt.Sprintf("Renewed token, it now expires in %s", ttl))
+		}
+		info.TTL = ttl
+	}
+
+	if ttl >= renewthreshold {
+		return -1
+	}
+	if ttl <= tokencheckinterval {
+		reauthenticate(g, fmt.Errorf("token expires in %s and can't be renewed", ttl))
+		return warned
+	}
+
+	level := time.Duration(-1)
+	for _, l := range expirywarnings {
+		if ttl <= l {
+			level = l
+		}
+	}
+	if level >= 0 && (warned < 0 || level < warned) {
+		logAsync(g, fmt.Sprintf("WARNING: token expires in %s", ttl.Round(time.Second)))
+		warned = level
+	}
+	return warned
+}
+
+func logAsync(g *gocui.Gui, msg string) {
+	g.Execute(func(g *gocui.Gui) error {
+		UpdateLog(g, msg)
+		return nil
+	})
+}
+
+func reauthenticate(g *gocui.Gui, reason error) {
+	g.Execute(func(g *gocui.Gui) error {
+		if loggingIn(g) {
+			return nil
+		}
+		return LoginScreen(g, reason)
+	})
+}
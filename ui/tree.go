@@ -0,0 +1,383 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jroimartin/gocui"
+	"github.com/rackerlabs/vault-commander/api"
+)
+
+// treeNode is an entry of the key tree shown in the main view. Folders
+// list their children the first time they are expanded, or earlier when
+// their child counts are fetched in the background.
+type treeNode struct {
+	path     string
+	name     string
+	depth    int
+	folder   bool
+	expanded bool
+	loaded   bool
+	children []*treeNode
+	parent   *treeNode
+
+	// label replaces the rendered line, as for filter matches.
+	label string
+}
+
+func (n *treeNode) setChildren(keys []string) {
+	n.children = make([]*treeNode, 0, len(keys))
+	for _, key := range keys {
+		n.children = append(n.children, &treeNode{
+			path:   n.path + key,
+			name:   key,
+			depth:  n.depth + 1,
+			folder: strings.HasSuffix(key, "/"),
+			parent: n,
+		})
+	}
+	n.loaded = true
+}
+
+// tree is the root of the key tree of the current mount and rows the nodes
+// shown in the main view, one per line. In flat mode rows holds every
+// secret on the mount instead.
+var tree *treeNode
+var rows []*treeNode
+var flatmode bool
+
+// treectx is cancelled with the listing the tree belongs to, which stops
+// the background fetching of child counts.
+var treectx = context.Background()
+
+// treelisted is when the top level of the tree was fetched from Vault and
+// stalewarned whether the log already says it is stale.
+var treelisted time.Time
+var stalewarned bool
+
+// prefetchworkers bounds how many folders are listed at once for their
+// child counts.
+var prefetchworkers = 8
+
+// loadTree shows the top level of mount in the main view. Reloading the
+// mount that is already shown keeps the folders that were expanded and the
+// path under the cursor.
+func loadTree(g *gocui.Gui, mount string) {
+	listcancel()
+	filtering = false
+	if mount == "" {
+		return
+	}
+	if flatmode {
+		loadKeys(g, mount)
+		return
+	}
+
+	selected := mainLine(g)
+	var expanded []string
+	if tree != nil && tree.path == mount {
+		expanded = expandedPaths(tree, nil)
+	} else {
+		selected = ""
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	listcancel = cancel
+	treectx = ctx
+	listing++
+
+	tree = &treeNode{path: mount, depth: -1, folder: true}
+	if err := expandNode(ctx, tree); err != nil {
+		UpdateLog(g, fmt.Sprintf("ERROR: unable to list %s: %s", mount, err))
+	}
+	for _, path := range expanded {
+		if n := findNode(tree, path); n != nil {
+			if err := expandNode(ctx, n); err != nil {
+				UpdateLog(g, fmt.Sprintf("ERROR: unable to list %s: %s", path, err))
+			}
+		}
+	}
+
+	treelisted, _ = api.ListedAt(mount)
+	stalewarned = false
+
+	setMainTitle(g, "Keys")
+	renderTree(g, selected)
+	prefetchCounts(g, rows)
+}
+
+// RefreshKeys fetches the keys of the current mount from Vault again
+// instead of the cache.
+func RefreshKeys(g *gocui.Gui, v *gocui.View) error {
+	if currentmount == "" {
+		return nil
+	}
+	api.Refresh(currentmount)
+	filtermount = ""
+	resetCapabilities()
+	loadTree(g, currentmount)
+	UpdateLegend(g, mainLegend(g))
+	UpdateLog(g, fmt.Sprintf("Refreshed keys of %s", currentmount))
+	return nil
+}
+
+// warnStale logs once when the keys shown were listed, or the secret open
+// was read, longer ago than they are cached for.
+func warnStale(g *gocui.Gui) {
+	if _, err := g.View("secret"); err == nil && opensecret != nil && !secretwarned {
+		if age := time.Since(secretread); age > api.CacheTTL {
+			UpdateLog(g, fmt.Sprintf("%s was read %s ago and may be stale, press q and open it again to refresh", opensecret.Path, age.Round(time.Second)))
+			secretwarned = true
+		}
+	}
+
+	if currentmount == "" || treelisted.IsZero() || stalewarned {
+		return
+	}
+	if age := time.Since(treelisted); age > api.CacheTTL {
+		UpdateLog(g, fmt.Sprintf("Keys of %s were listed %s ago and may be stale, press R to refresh", currentmount, age.Round(time.Second)))
+		stalewarned = true
+	}
+}
+
+// expandNode lists the children of n if that wasn't done yet and opens it.
+func expandNode(ctx context.Context, n *treeNode) error {
+	if !n.loaded {
+		keys, err := store.List(ctx, n.path)
+		if err != nil {
+			return err
+		}
+		n.setChildren(keys)
+	}
+	n.expanded = true
+	return nil
+}
+
+// expandedPaths returns the open folders under n, parents first.
+func expandedPaths(n *treeNode, paths []string) []string {
+	for _, c := range n.children {
+		if c.expanded {
+			paths = append(paths, c.path)
+			paths = expandedPaths(c, paths)
+		}
+	}
+	return paths
+}
+
+func findNode(n *treeNode, path string) *treeNode {
+	if n.path == path {
+		return n
+	}
+	for _, c := range n.children {
+		if c.path == path || (c.folder && strings.HasPrefix(path, c.path)) {
+			return findNode(c, path)
+		}
+	}
+	return nil
+}
+
+func visibleNodes(n *treeNode, nodes []*treeNode) []*treeNode {
+	for _, c := range n.children {
+		nodes = append(nodes, c)
+		if c.expanded {
+			nodes = visibleNodes(c, nodes)
+		}
+	}
+	return nodes
+}
+
+// prefetchCounts lists the unopened folders among nodes in the background
+// so their child counts can be shown and they open without a wait.
+func prefetchCounts(g *gocui.Gui, nodes []*treeNode) {
+	var folders []*treeNode
+	for _, n := range nodes {
+		if n.folder && !n.loaded {
+			folders = append(folders, n)
+		}
+	}
+	if len(folders) == 0 {
+		return
+	}
+
+	ctx := treectx
+	id := listing
+	go func() {
+		sem := make(chan struct{}, prefetchworkers)
+		for _, n := range folders {
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			go func(n *treeNode, path string) {
+				defer func() { <-sem }()
+				keys, err := store.List(ctx, path)
+				if err != nil {
+					return
+				}
+				g.Execute(func(g *gocui.Gui) error {
+					if id != listing || n.loaded {
+						return nil
+					}
+					n.setChildren(keys)
+					renderTree(g, mainLine(g))
+					return nil
+				})
+			}(n, n.path)
+		}
+	}()
+}
+
+// renderTree redraws the tree, keeping the cursor on selected.
+func renderTree(g *gocui.Gui, selected string) {
+	if tree == nil {
+		return
+	}
+	rows = visibleNodes(tree, nil)
+	drawRows(g, selected)
+}
+
+// drawRows fills the main view with rows and puts the cursor on selected,
+// or on the same line as before when selected is no longer shown.
+func drawRows(g *gocui.Gui, selected string) {
+	v, err := g.View("main")
+	if err != nil {
+		return
+	}
+	_, oy := v.Origin()
+	_, cy := v.Cursor()
+	current := oy + cy
+
+	v.Clear()
+	for i, n := range rows {
+		fmt.Fprintln(v, rowText(n))
+		if n.path == selected {
+			current = i
+		}
+	}
+	if current >= len(rows) {
+		current = len(rows) - 1
+	}
+	if current < 0 {
+		current = 0
+	}
+	selectRow(v, current)
+}
+
+func rowText(n *treeNode) string {
+	if n.label != "" {
+		return n.label
+	}
+	if flatmode {
+		return n.name
+	}
+	indent := strings.Repeat("  ", n.depth)
+	if !n.folder {
+		return indent + "  " + n.name
+	}
+	marker := "▸ "
+	if n.expanded {
+		marker = "▾ "
+	}
+	text := indent + marker + n.name
+	if n.loaded {
+		text += fmt.Sprintf(" (%d)", len(n.children))
+	}
+	return text
+}
+
+// selectRow moves the cursor to line i, scrolling it into view.
+func selectRow(v *gocui.View, i int) {
+	_, h := v.Size()
+	_, oy := v.Origin()
+	if i < oy {
+		oy = i
+	} else if h > 0 && i >= oy+h {
+		oy = i - h + 1
+	}
+	v.SetOrigin(0, oy)
+	v.SetCursor(0, i-oy)
+}
+
+// selectedNode returns the node under the cursor of the main view.
+func selectedNode(g *gocui.Gui) *treeNode {
+	v, err := g.View("main")
+	if err != nil {
+		return nil
+	}
+	_, oy := v.Origin()
+	_, cy := v.Cursor()
+	if i := oy + cy; i >= 0 && i < len(rows) {
+		return rows[i]
+	}
+	return nil
+}
+
+// OpenNode opens or closes the folder under the cursor, or shows the
+// secret.
+func OpenNode(g *gocui.Gui, v *gocui.View) error {
+	n := selectedNode(g)
+	if n == nil {
+		return nil
+	}
+	if !n.folder {
+		return ViewSecret(g, v)
+	}
+	if n.expanded {
+		return CollapseNode(g, v)
+	}
+	return ExpandNode(g, v)
+}
+
+func ExpandNode(g *gocui.Gui, v *gocui.View) error {
+	n := selectedNode(g)
+	if n == nil || !n.folder || flatmode {
+		return nil
+	}
+	if n.expanded {
+		if len(n.children) > 0 {
+			renderTree(g, n.children[0].path)
+			UpdateLegend(g, mainLegend(g))
+		}
+		return nil
+	}
+	if err := expandNode(context.Background(), n); err != nil {
+		UpdateLog(g, fmt.Sprintf("ERROR: unable to list %s: %s", n.path, err))
+		return nil
+	}
+	renderTree(g, n.path)
+	prefetchCounts(g, n.children)
+	return nil
+}
+
+func CollapseNode(g *gocui.Gui, v *gocui.View) error {
+	n := selectedNode(g)
+	if n == nil || flatmode {
+		return nil
+	}
+	if !n.expanded {
+		// On a secret or a closed folder go up to the parent folder.
+		if n.parent == nil || n.parent == tree {
+			return nil
+		}
+		n = n.parent
+	}
+	n.expanded = false
+	renderTree(g, n.path)
+	UpdateLegend(g, mainLegend(g))
+	return nil
+}
+
+// ToggleFlat switches the main view between the tree and a flat list of
+// every secret on the mount.
+func ToggleFlat(g *gocui.Gui, v *gocui.View) error {
+	flatmode = !flatmode
+	if !flatmode {
+		setMainTitle(g, "Keys")
+	}
+	loadTree(g, currentmount)
+	UpdateLegend(g, mainLegend(g))
+	return nil
+}
@@ -0,0 +1,38 @@
+package ui
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTreeNodes(t *testing.T) {
+	root := &treeNode{path: "secret/", depth: -1, folder: true, expanded: true}
+	root.setChildren([]string{"app/", "db"})
+	app := root.children[0]
+	app.setChildren([]string{"prod/", "token"})
+	app.expanded = true
+
+	var paths []string
+	for _, n := range visibleNodes(root, nil) {
+		paths = append(paths, rowText(n))
+	}
+	expected := []string{"▾ app/ (2)", "  ▸ prod/", "    token", "  db"}
+	if !reflect.DeepEqual(paths, expected) {
+		t.Errorf("Test failed, expected: '%v', got:  '%v'", expected, paths)
+	}
+
+	if n := findNode(root, "secret/app/token"); n == nil || n.parent != app {
+		t.Errorf("Test failed, expected: 'secret/app/token', got:  '%v'", n)
+	}
+	if got := expandedPaths(root, nil); !reflect.DeepEqual(got, []string{"secret/app/"}) {
+		t.Errorf("Test failed, expected: '%v', got:  '%v'", []string{"secret/app/"}, got)
+	}
+}
+
+func TestLegendColumns(t *testing.T) {
+	got := legendColumns("a - add\nd - delete\nq - quit", 2)
+	expected := "a - add     q - quit\nd - delete  "
+	if got != expected {
+		t.Errorf("Test failed, expected: '%v', got:  '%v'", expected, got)
+	}
+}
@@ -0,0 +1,308 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jroimartin/gocui"
+	"github.com/rackerlabs/vault-commander/api"
+)
+
+var versionslegend = "Space - mark version\nRet - view version\nr - restore version\nu - undelete marked\nD - destroy marked\nq - back"
+var versionlegend = "q - back to versions"
+
+// versionlist holds the versions shown in the versions view and
+// markedversions the ones selected for undelete or destroy.
+var versionlist []api.Version
+var markedversions = map[int]bool{}
+
+func VersionHistory(g *gocui.Gui, v *gocui.View) error {
+	secretpath := secretPath(g)
+	if secretpath == "" {
+		return nil
+	}
+
+	if !api.IsKVv2(secretpath) {
+		UpdateLog(g, fmt.Sprintf("Version history is only available on KV v2 mounts, %s is not on one", secretpath))
+		return nil
+	}
+
+	versions, err := api.ListVersions(secretpath)
+	if err != nil {
+		UpdateLog(g, err.Error())
+		return nil
+	}
+	versionlist = versions
+	markedversions = map[int]bool{}
+
+	maxX, maxY := g.Size()
+	v = CreateView(g, "versions", 1, 1, maxX-1, maxY-10)
+	v.Title = fmt.Sprintf("Versions of %s", secretpath)
+	v.Highlight = true
+	v.SelBgColor = gocui.ColorGreen
+	v.SelFgColor = gocui.ColorBlack
+	renderVersions(v)
+	if err := v.SetCursor(0, 0); err != nil {
+		return err
+	}
+	if err := v.SetOrigin(0, 0); err != nil {
+		return err
+	}
+
+	UpdateLegend(g, versionslegend)
+	UpdateLog(g, fmt.Sprintf("Viewing version history of %s", secretpath))
+	return nil
+}
+
+func ViewVersion(g *gocui.Gui, v *gocui.View) error {
+	version := selectedVersion(v)
+	if version == 0 {
+		return nil
+	}
+	secretpath := secretPath(g)
+
+	contents, err := api.ReadVersion(secretpath, version)
+	if err != nil {
+		UpdateLog(g, err.Error())
+		return nil
+	}
+
+	maxX, maxY := g.Size()
+	v = CreateView(g, "versionsecret", -1, 0, maxX, maxY-9)
+	v.Clear()
+	fmt.Fprintln(v, contents)
+	UpdateLegend(g, versionlegend)
+	UpdateLog(g, fmt.Sprintf("Viewing version %d of %s (read-only)", version, secretpath))
+	return nil
+}
+
+func CloseVersion(g *gocui.Gui, v *gocui.View) error {
+	g.DeleteView("versionsecret")
+	if _, err := g.SetCurrentView("versions"); err != nil {
+		return err
+	}
+	UpdateLegend(g, versionslegend)
+	return nil
+}
+
+func CloseVersions(g *gocui.Gui, v *gocui.View) error {
+	g.DeleteView("versionsecret")
+	g.DeleteView("versions")
+	if _, err := g.SetCurrentView("secret"); err != nil {
+		return err
+	}
+	UpdateLegend(g, secretLegend(g))
+	return nil
+}
+
+func MarkVersion(g *gocui.Gui, v *gocui.View) error {
+	version := selectedVersion(v)
+	if version == 0 {
+		return nil
+	}
+	markedversions[version] = !markedversions[version]
+	renderVersions(v)
+	return nil
+}
+
+func RestorePrompt(g *gocui.Gui, v *gocui.View) error {
+	version := selectedVersion(v)
+	if version == 0 {
+		return nil
+	}
+	secretpath := secretPath(g)
+
+	prompt := fmt.Sprintf("Restore version %d of %s as current? (y/n)", version, secretpath)
+	maxX, maxY := g.Size()
+	v = CreateView(g, "restoreprompt", maxX/2-len(prompt)/2-1, maxY/2, maxX/2+len(prompt)/2+1, maxY/2+2)
+	fmt.Fprintln(v, prompt)
+	return nil
+}
+
+func RestoreVersion(g *gocui.Gui, v *gocui.View) error {
+	x, _ := g.View("versions")
+	version := selectedVersion(x)
+	secretpath := secretPath(g)
+
+	data, err := api.Rollback(store, secretpath, opensecret, version)
+	if conflicterr, ok := err.(*api.ConflictError); ok {
+		// Restoring is an edit back to the old contents, so a conflict is
+		// merged the same way as when saving an edit.
+		UpdateLog(g, fmt.Sprintf("ERROR: %s.", conflicterr))
+		g.DeleteView("versionsecret")
+		g.DeleteView("versions")
+		editmode = "Editing"
+		maxX, maxY := g.Size()
+		ev := CreateView(g, "editsecret", -1, 0, maxX, maxY-9)
+		ev.Clear()
+		fmt.Fprintln(ev, (&api.Secret{Data: data}).String())
+		var base map[string]interface{}
+		if opensecret != nil {
+			base = opensecret.Data
+		}
+		return ResolveConflict(g, secretpath, base, data)
+	} else if err != nil {
+		UpdateLog(g, err.Error())
+	} else {
+		UpdateLog(g, fmt.Sprintf("Restored version %d of %s as the current version", version, secretpath))
+		reloadSecret(g)
+	}
+	return refreshVersions(g)
+}
+
+func UndeleteVersions(g *gocui.Gui, v *gocui.View) error {
+	versions := versionsToChange(v)
+	if len(versions) == 0 {
+		return nil
+	}
+	secretpath := secretPath(g)
+
+	if err := api.Undelete(secretpath, versions); err != nil {
+		UpdateLog(g, err.Error())
+	} else {
+		UpdateLog(g, fmt.Sprintf("Undeleted version(s) %s of %s", joinVersions(versions), secretpath))
+		reloadSecret(g)
+	}
+	return refreshVersions(g)
+}
+
+func DestroyPrompt(g *gocui.Gui, v *gocui.View) error {
+	versions := versionsToChange(v)
+	if len(versions) == 0 {
+		return nil
+	}
+	secretpath := secretPath(g)
+
+	title := fmt.Sprintf("Type %s to destroy version(s) %s", secretpath, joinVersions(versions))
+	maxX, maxY := g.Size()
+	v = CreateView(g, "destroyprompt", maxX/2-len(title)/2-2, maxY/2, maxX/2+len(title)/2+2, maxY/2+2)
+	v.Title = title
+	return nil
+}
+
+func DestroyVersions(g *gocui.Gui, v *gocui.View) error {
+	x, _ := g.View("versions")
+	versions := versionsToChange(x)
+	secretpath := secretPath(g)
+
+	if strings.TrimSpace(v.Buffer()) != secretpath {
+		UpdateLog(g, "Confirmation did not match the secret path. Destroy cancelled.")
+		return refreshVersions(g)
+	}
+
+	if err := api.Destroy(secretpath, versions); err != nil {
+		UpdateLog(g, err.Error())
+	} else {
+		UpdateLog(g, fmt.Sprintf("Permanently destroyed version(s) %s of %s", joinVersions(versions), secretpath))
+		reloadSecret(g)
+	}
+	return refreshVersions(g)
+}
+
+func CancelVersionPrompt(g *gocui.Gui, v *gocui.View) error {
+	g.DeleteView("restoreprompt")
+	g.DeleteView("destroyprompt")
+	if _, err := g.SetCurrentView("versions"); err != nil {
+		return err
+	}
+	UpdateLegend(g, versionslegend)
+	return nil
+}
+
+// reloadSecret reads the open secret again after its versions changed and
+// redraws the secret view, so an edit starts from the current version.
+func reloadSecret(g *gocui.Gui) {
+	if opensecret == nil {
+		return
+	}
+	secret, err := store.Read(api.NoCache(context.Background()), opensecret.Path)
+	if err != nil {
+		UpdateLog(g, fmt.Sprintf("ERROR: unable to read %s again: %s", opensecret.Path, err))
+		return
+	}
+	opensecret = secret
+	secretread = time.Now()
+	secretwarned = false
+	if v, err := g.View("secret"); err == nil {
+		v.Clear()
+		fmt.Fprintln(v, secret.String())
+	}
+}
+
+// refreshVersions closes any version prompt and reloads the version list.
+func refreshVersions(g *gocui.Gui) error {
+	CancelVersionPrompt(g, nil)
+	v, _ := g.View("versions")
+	return VersionHistory(g, v)
+}
+
+func renderVersions(v *gocui.View) {
+	v.Clear()
+	for _, ver := range versionlist {
+		marker := " "
+		if markedversions[ver.Version] {
+			marker = "*"
+		}
+		fmt.Fprintln(v, marker, formatVersion(ver))
+	}
+}
+
+// versionsToChange returns the marked versions, or the version under the
+// cursor when nothing is marked.
+func versionsToChange(v *gocui.View) []int {
+	var versions []int
+	for _, ver := range versionlist {
+		if markedversions[ver.Version] {
+			versions = append(versions, ver.Version)
+		}
+	}
+	if len(versions) == 0 {
+		if version := selectedVersion(v); version != 0 {
+			versions = append(versions, version)
+		}
+	}
+	return versions
+}
+
+func joinVersions(versions []int) string {
+	s := make([]string, len(versions))
+	for i, n := range versions {
+		s[i] = strconv.Itoa(n)
+	}
+	return strings.Join(s, ", ")
+}
+
+func formatVersion(ver api.Version) string {
+	timeForm := "2006-01-02 15:04:05"
+	line := fmt.Sprintf("v%-4d created %s", ver.Version, ver.CreatedTime.Local().Format(timeForm))
+	if ver.Deleted() {
+		line += "  deleted " + ver.DeletionTime.Local().Format(timeForm)
+	}
+	if ver.Destroyed {
+		line += "  destroyed"
+	}
+	return line
+}
+
+// selectedVersion returns the version number under the cursor of the
+// versions view, or 0 if there is none.
+func selectedVersion(v *gocui.View) int {
+	_, cy := v.Cursor()
+	line, err := v.Line(cy)
+	if err != nil {
+		return 0
+	}
+	for _, field := range strings.Fields(line) {
+		if strings.HasPrefix(field, "v") {
+			n, err := strconv.Atoi(strings.TrimPrefix(field, "v"))
+			if err != nil {
+				return 0
+			}
+			return n
+		}
+	}
+	return 0
+}
@@ -3,7 +3,9 @@ package ui
 import (
 	"fmt"
 	"log"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/jroimartin/gocui"
 	"github.com/rackerlabs/vault-commander/api"
@@ -13,9 +15,21 @@ func init() {
 	le = lineEditor{gocui.DefaultEditor}
 }
 
+// legendwidth is the width of the legend view, room for two columns.
+var legendwidth = 46
+
 func MainScreen(g *gocui.Gui) error {
 	maxX, maxY := g.Size()
 
+	if v, err := g.SetView("status", -1, -1, maxX, 1); err != nil {
+		if err != gocui.ErrUnknownView {
+			return err
+		}
+		v.Editable = false
+		v.Frame = false
+		v.BgColor = gocui.ColorBlue
+		v.FgColor = gocui.ColorWhite
+	}
 	if v, err := g.SetView("side", 1, 1, 30, maxY-10); err != nil {
 		if err != gocui.ErrUnknownView {
 			return err
@@ -25,7 +39,7 @@ func MainScreen(g *gocui.Gui) error {
 		v.Title = "Mounts"
 		v.SelBgColor = gocui.ColorGreen
 		v.SelFgColor = gocui.ColorBlack
-		for _, mount := range api.ListMounts() {
+		for _, mount := range store.Mounts() {
 			fmt.Fprintln(v, mount)
 		}
 	}
@@ -36,9 +50,9 @@ func MainScreen(g *gocui.Gui) error {
 		v.Editable = false
 		v.Frame = true
 		v.Title = "Keys"
-		v.Wrap = true
+		v.Wrap = false
 	}
-	if v, err := g.SetView("legend", maxX-24, maxY-9, maxX-1, maxY-1); err != nil {
+	if v, err := g.SetView("legend", maxX-legendwidth, maxY-9, maxX-1, maxY-1); err != nil {
 		if err != gocui.ErrUnknownView {
 			return err
 		}
@@ -46,7 +60,7 @@ func MainScreen(g *gocui.Gui) error {
 		v.Frame = true
 		v.Title = "Legend"
 	}
-	if v, err := g.SetView("log", 1, maxY-9, maxX-25, maxY-1); err != nil {
+	if v, err := g.SetView("log", 1, maxY-9, maxX-legendwidth-1, maxY-1); err != nil {
 		if err != gocui.ErrUnknownView {
 			return err
 		}
@@ -58,8 +72,18 @@ func MainScreen(g *gocui.Gui) error {
 	return nil
 }
 
-func InitScreen(g *gocui.Gui, mp string) error {
+// InitScreen draws the screen with secrets from s and opens mount mp, if
+// given.
+func InitScreen(g *gocui.Gui, s api.SecretStore, mp string) error {
+	store = s
 	MainScreen(g)
+	if err := api.CheckToken(); needsLogin(err) {
+		pendingmount = mp
+		return LoginScreen(g, err)
+	} else if err != nil {
+		UpdateLog(g, err.Error())
+	}
+
 	if mp != "" {
 		initMount(g, mp)
 	} else {
@@ -89,19 +113,51 @@ func initMount(g *gocui.Gui, mp string) error {
 	v.Highlight = true
 	v.SelBgColor = gocui.ColorGreen
 	v.SelFgColor = gocui.ColorBlack
-	v.Clear()
-	var keys []string
-	api.ListAllKeys(keys, fmt.Sprintf("%s/", mp), "", v)
+	resetCapabilities()
+	currentmount = fmt.Sprintf("%s/", mp)
+	UpdateStatus(g)
 	g.SetCurrentView("main")
-	UpdateLegend(g, mainlegend)
-	UpdateLog(g, fmt.Sprintf("Viewing secrets on %s mount", mp))
+	loadTree(g, currentmount)
+	UpdateLegend(g, mainLegend(g))
 	return nil
 }
 
+// legendtext is the legend currently shown.
+var legendtext string
+
 func UpdateLegend(g *gocui.Gui, legend string) {
+	legendtext = legend
 	v, _ := g.View("legend")
 	v.Clear()
-	fmt.Fprintln(v, legend)
+	_, h := v.Size()
+	fmt.Fprintln(v, legendColumns(legend, h))
+}
+
+// legendColumns lays legend out in as many columns as it takes to fit in
+// height lines.
+func legendColumns(legend string, height int) string {
+	lines := strings.Split(legend, "\n")
+	if height <= 0 || len(lines) <= height {
+		return legend
+	}
+
+	columns := (len(lines) + height - 1) / height
+	widths := make([]int, columns)
+	for i, line := range lines {
+		if w := utf8.RuneCountInString(line); w > widths[i/height] {
+			widths[i/height] = w
+		}
+	}
+
+	out := make([]string, height)
+	for i, line := range lines {
+		row, col := i%height, i/height
+		if col < columns-1 {
+			line += strings.Repeat(" ", widths[col]-utf8.RuneCountInString(line)+2)
+		}
+		out[row] += line
+	}
+	return strings.Join(out, "\n")
 }
 
 func UpdateLog(g *gocui.Gui, log string) {
@@ -177,6 +233,198 @@ var vp = map[string]viewProperties{
 		title:      "Insert Key Name",
 		wrap:       false,
 	},
+	"versions": {
+		autoscroll: false,
+		editable:   false,
+		editor:     gocui.DefaultEditor,
+		frame:      true,
+		title:      "Versions",
+		wrap:       false,
+	},
+	"versionsecret": {
+		autoscroll: false,
+		editable:   false,
+		editor:     gocui.DefaultEditor,
+		frame:      false,
+		title:      "",
+		wrap:       true,
+	},
+	"restoreprompt": {
+		autoscroll: false,
+		editable:   false,
+		editor:     gocui.DefaultEditor,
+		frame:      true,
+		title:      "Restore Version",
+		wrap:       false,
+	},
+	"destroyprompt": {
+		autoscroll: false,
+		editable:   true,
+		editor:     &le,
+		frame:      true,
+		title:      "WARNING",
+		wrap:       false,
+	},
+	"conflictbase": {
+		autoscroll: false,
+		editable:   false,
+		editor:     gocui.DefaultEditor,
+		frame:      true,
+		title:      "Original",
+		wrap:       true,
+	},
+	"conflictmine": {
+		autoscroll: false,
+		editable:   false,
+		editor:     gocui.DefaultEditor,
+		frame:      true,
+		title:      "Mine",
+		wrap:       true,
+	},
+	"conflicttheirs": {
+		autoscroll: false,
+		editable:   false,
+		editor:     gocui.DefaultEditor,
+		frame:      true,
+		title:      "Server",
+		wrap:       true,
+	},
+	"conflictfields": {
+		autoscroll: false,
+		editable:   false,
+		editor:     gocui.DefaultEditor,
+		frame:      true,
+		title:      "Conflicting Fields",
+		wrap:       false,
+	},
+	"login": {
+		autoscroll: false,
+		editable:   false,
+		editor:     gocui.DefaultEditor,
+		frame:      true,
+		title:      "Login Method",
+		wrap:       false,
+	},
+	"loginfield": {
+		autoscroll: false,
+		editable:   true,
+		editor:     &le,
+		frame:      true,
+		title:      "",
+		wrap:       false,
+	},
+	"savetokenprompt": {
+		autoscroll: false,
+		editable:   false,
+		editor:     gocui.DefaultEditor,
+		frame:      true,
+		title:      "Save Token",
+		wrap:       false,
+	},
+	"profiles": {
+		autoscroll: false,
+		editable:   false,
+		editor:     gocui.DefaultEditor,
+		frame:      true,
+		title:      "Profiles",
+		wrap:       false,
+	},
+	"namespaces": {
+		autoscroll: false,
+		editable:   false,
+		editor:     gocui.DefaultEditor,
+		frame:      true,
+		title:      "Namespaces",
+		wrap:       false,
+	},
+	"filter": {
+		autoscroll: false,
+		editable:   true,
+		editor:     &fe,
+		frame:      true,
+		title:      "Filter",
+		wrap:       false,
+	},
+	"searchprompt": {
+		autoscroll: false,
+		editable:   true,
+		editor:     &le,
+		frame:      true,
+		title:      "Search Under",
+		wrap:       false,
+	},
+	"exportprompt": {
+		autoscroll: false,
+		editable:   true,
+		editor:     &le,
+		frame:      true,
+		title:      "Export Folder",
+		wrap:       false,
+	},
+	"deletefolder": {
+		autoscroll: false,
+		editable:   false,
+		editor:     gocui.DefaultEditor,
+		frame:      true,
+		title:      "Delete Folder",
+		wrap:       false,
+	},
+	"deleteconfirm": {
+		autoscroll: false,
+		editable:   true,
+		editor:     &le,
+		frame:      true,
+		title:      "Confirm",
+		wrap:       false,
+	},
+	"compareleft": {
+		autoscroll: false,
+		editable:   false,
+		editor:     gocui.DefaultEditor,
+		frame:      true,
+		title:      "Compare",
+		wrap:       false,
+	},
+	"compareright": {
+		autoscroll: false,
+		editable:   false,
+		editor:     gocui.DefaultEditor,
+		frame:      true,
+		title:      "Compare",
+		wrap:       false,
+	},
+	"copyprompt": {
+		autoscroll: false,
+		editable:   true,
+		editor:     &le,
+		frame:      true,
+		title:      "Copy To",
+		wrap:       false,
+	},
+	"importprompt": {
+		autoscroll: false,
+		editable:   true,
+		editor:     &le,
+		frame:      true,
+		title:      "Import File",
+		wrap:       false,
+	},
+	"import": {
+		autoscroll: false,
+		editable:   false,
+		editor:     gocui.DefaultEditor,
+		frame:      true,
+		title:      "Import",
+		wrap:       false,
+	},
+	"search": {
+		autoscroll: false,
+		editable:   false,
+		editor:     gocui.DefaultEditor,
+		frame:      true,
+		title:      "Search",
+		wrap:       false,
+	},
 	"saveprompt": {
 		autoscroll: false,
 		editable:   false,
@@ -194,7 +442,7 @@ func Keybindings(g *gocui.Gui) error {
 	if err := g.SetKeybinding("side", gocui.KeyEnter, gocui.ModNone, GetLine); err != nil {
 		return err
 	}
-	if err := g.SetKeybinding("main", gocui.KeyEnter, gocui.ModNone, ViewSecret); err != nil {
+	if err := g.SetKeybinding("main", gocui.KeyEnter, gocui.ModNone, OpenNode); err != nil {
 		return err
 	}
 	if err := g.SetKeybinding("main", gocui.KeyTab, gocui.ModNone, NextView); err != nil {
@@ -218,13 +466,13 @@ func Keybindings(g *gocui.Gui) error {
 	if err := g.SetKeybinding("side", gocui.KeyArrowDown, gocui.ModNone, CursorDown); err != nil {
 		return err
 	}
-	if err := g.SetKeybinding("main", gocui.KeyArrowUp, gocui.ModNone, CursorUp); err != nil {
+	if err := g.SetKeybinding("main", gocui.KeyArrowUp, gocui.ModNone, MainCursorUp); err != nil {
 		return err
 	}
-	if err := g.SetKeybinding("main", gocui.KeyArrowDown, gocui.ModNone, CursorDown); err != nil {
+	if err := g.SetKeybinding("main", gocui.KeyArrowDown, gocui.ModNone, MainCursorDown); err != nil {
 		return err
 	}
-	if err := g.SetKeybinding("main", gocui.KeySpace, gocui.ModNone, PageDown); err != nil {
+	if err := g.SetKeybinding("main", gocui.KeySpace, gocui.ModNone, MainPageDown); err != nil {
 		return err
 	}
 	if err := g.SetKeybinding("side", gocui.KeyArrowUp, gocui.ModNone, CursorUp); err != nil {
@@ -233,7 +481,7 @@ func Keybindings(g *gocui.Gui) error {
 	if err := g.SetKeybinding("", gocui.KeyCtrlC, gocui.ModNone, Quit); err != nil {
 		return err
 	}
-	if err := g.SetKeybinding("secret", 'q', gocui.ModNone, MainView); err != nil {
+	if err := g.SetKeybinding("secret", 'q', gocui.ModNone, CloseSecret); err != nil {
 		return err
 	}
 	if err := g.SetKeybinding("editsecret", gocui.KeyCtrlX, gocui.ModNone, CancelEdit); err != nil {
@@ -248,11 +496,254 @@ func Keybindings(g *gocui.Gui) error {
 	if err := g.SetKeybinding("saveprompt", 'n', gocui.ModNone, DeletePrompt); err != nil {
 		return err
 	}
+	if err := g.SetKeybinding("saveprompt", 'v', gocui.ModNone, RevealSave); err != nil {
+		return err
+	}
 	if err := g.SetKeybinding("editsecret", gocui.KeyCtrlS, gocui.ModNone, SavePrompt); err != nil {
 		return err
 	}
 	if err := g.SetKeybinding("secret", 'e', gocui.ModNone, EditSecret); err != nil {
 		return err
 	}
+	if err := g.SetKeybinding("secret", 'h', gocui.ModNone, VersionHistory); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("versions", gocui.KeyArrowUp, gocui.ModNone, CursorUp); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("versions", gocui.KeyArrowDown, gocui.ModNone, CursorDown); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("versions", gocui.KeyEnter, gocui.ModNone, ViewVersion); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("versions", 'q', gocui.ModNone, CloseVersions); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("versionsecret", 'q', gocui.ModNone, CloseVersion); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("versions", gocui.KeySpace, gocui.ModNone, MarkVersion); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("versions", 'r', gocui.ModNone, RestorePrompt); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("versions", 'u', gocui.ModNone, UndeleteVersions); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("versions", 'D', gocui.ModNone, DestroyPrompt); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("restoreprompt", 'y', gocui.ModNone, RestoreVersion); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("restoreprompt", 'n', gocui.ModNone, CancelVersionPrompt); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("destroyprompt", gocui.KeyEnter, gocui.ModNone, DestroyVersions); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("destroyprompt", gocui.KeyCtrlX, gocui.ModNone, CancelVersionPrompt); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("conflictfields", gocui.KeyArrowUp, gocui.ModNone, CursorUp); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("conflictfields", gocui.KeyArrowDown, gocui.ModNone, CursorDown); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("conflictfields", gocui.KeyArrowLeft, gocui.ModNone, KeepMine); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("conflictfields", gocui.KeyArrowRight, gocui.ModNone, TakeTheirs); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("conflictfields", gocui.KeyCtrlS, gocui.ModNone, SaveMerged); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("conflictfields", gocui.KeyCtrlX, gocui.ModNone, CancelConflict); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("login", gocui.KeyArrowUp, gocui.ModNone, CursorUp); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("login", gocui.KeyArrowDown, gocui.ModNone, CursorDown); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("login", gocui.KeyEnter, gocui.ModNone, ChooseLoginMethod); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("loginfield", gocui.KeyEnter, gocui.ModNone, NextLoginField); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("loginfield", gocui.KeyCtrlX, gocui.ModNone, CancelLoginField); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("savetokenprompt", 'y', gocui.ModNone, SaveLoginToken); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("savetokenprompt", 'n', gocui.ModNone, SkipLoginToken); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("side", 'p', gocui.ModNone, ProfilePicker); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("profiles", gocui.KeyArrowUp, gocui.ModNone, CursorUp); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("profiles", gocui.KeyArrowDown, gocui.ModNone, CursorDown); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("profiles", gocui.KeyEnter, gocui.ModNone, SwitchProfile); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("profiles", 'q', gocui.ModNone, CloseProfiles); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("side", 'n', gocui.ModNone, NamespaceBrowser); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("namespaces", gocui.KeyArrowUp, gocui.ModNone, CursorUp); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("namespaces", gocui.KeyArrowDown, gocui.ModNone, CursorDown); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("namespaces", gocui.KeyEnter, gocui.ModNone, EnterNamespace); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("namespaces", 'q', gocui.ModNone, CloseNamespaces); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("main", gocui.KeyCtrlX, gocui.ModNone, CancelListing); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("main", gocui.KeyArrowRight, gocui.ModNone, ExpandNode); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("main", gocui.KeyArrowLeft, gocui.ModNone, CollapseNode); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("main", 'f', gocui.ModNone, ToggleFlat); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("main", '/', gocui.ModNone, FilterPrompt); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("filter", gocui.KeyArrowUp, gocui.ModNone, FilterCursorUp); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("filter", gocui.KeyArrowDown, gocui.ModNone, FilterCursorDown); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("filter", gocui.KeyEnter, gocui.ModNone, OpenFilterMatch); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("filter", gocui.KeyCtrlX, gocui.ModNone, ClearFilter); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("main", 's', gocui.ModNone, SearchPrompt); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("main", 'R', gocui.ModNone, RefreshKeys); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("searchprompt", gocui.KeyEnter, gocui.ModNone, SearchNext); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("searchprompt", gocui.KeyCtrlX, gocui.ModNone, CancelSearchPrompt); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("search", gocui.KeyArrowUp, gocui.ModNone, CursorUp); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("search", gocui.KeyArrowDown, gocui.ModNone, CursorDown); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("search", gocui.KeyEnter, gocui.ModNone, OpenSearchResult); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("search", gocui.KeyCtrlX, gocui.ModNone, StopSearch); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("search", 'q', gocui.ModNone, CloseSearch); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("main", 'x', gocui.ModNone, ExportPrompt); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("exportprompt", gocui.KeyEnter, gocui.ModNone, ExportNext); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("exportprompt", gocui.KeyCtrlX, gocui.ModNone, CancelExportPrompt); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("main", 'c', gocui.ModNone, CopyPrompt); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("main", 'm', gocui.ModNone, MovePrompt); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("copyprompt", gocui.KeyEnter, gocui.ModNone, CopyTo); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("copyprompt", gocui.KeyCtrlX, gocui.ModNone, CancelCopyPrompt); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("deleteconfirm", gocui.KeyEnter, gocui.ModNone, DeleteFolder); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("deleteconfirm", gocui.KeyArrowUp, gocui.ModNone, ScrollDeleteUp); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("deleteconfirm", gocui.KeyArrowDown, gocui.ModNone, ScrollDeleteDown); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("deleteconfirm", gocui.KeyCtrlX, gocui.ModNone, CancelDeleteFolder); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("main", '=', gocui.ModNone, MarkCompare); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("compareleft", gocui.KeyArrowUp, gocui.ModNone, ScrollCompareUp); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("compareleft", gocui.KeyArrowDown, gocui.ModNone, ScrollCompareDown); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("compareleft", 'v', gocui.ModNone, RevealCompare); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("compareleft", 'q', gocui.ModNone, CloseCompare); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("main", 'i', gocui.ModNone, ImportPrompt); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("importprompt", gocui.KeyEnter, gocui.ModNone, ImportNext); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("importprompt", gocui.KeyCtrlX, gocui.ModNone, CancelImportPrompt); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("import", gocui.KeyArrowUp, gocui.ModNone, CursorUp); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("import", gocui.KeyArrowDown, gocui.ModNone, CursorDown); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("import", gocui.KeyEnter, gocui.ModNone, ApplyImport); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("import", 'r', gocui.ModNone, ToggleImportMode); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("import", 'v', gocui.ModNone, RevealImport); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("import", 'q', gocui.ModNone, CancelImport); err != nil {
+		return err
+	}
 	return nil
 }